@@ -0,0 +1,84 @@
+// Package arptest provides a fake network connection and clock for testing
+// code built on package arp, without privileges, network interfaces or
+// real timeouts.
+//
+// NewFakeClient creates an arp.Client whose frames are exchanged with a
+// Conn: queue the frames the Client should read, such as replies from fake
+// hosts, and inspect the frames it wrote. Time only moves when the Conn's
+// Clock is advanced, so timeouts are fast and deterministic:
+//
+//	c, conn, err := arptest.NewFakeClient()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer c.Close()
+//
+//	ip := net.IPv4(192, 0, 2, 2)
+//	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+//	if err := conn.QueueReply(ip, mac); err != nil {
+//		t.Fatal(err)
+//	}
+//
+//	got, err := c.Resolve(ip)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	if got.String() != mac.String() || len(conn.WrittenPackets()) != 1 {
+//		t.Fatalf("unexpected reply %s, or not exactly one request sent", got)
+//	}
+//
+//	// Without a reply, Resolve times out once the Clock passes its
+//	// deadline.
+//	errC := make(chan error)
+//	go func() {
+//		_, err := c.Resolve(net.IPv4(192, 0, 2, 3))
+//		errC <- err
+//	}()
+//	conn.WaitRead()
+//	conn.Clock().Advance(arp.DefaultTimeout)
+//	if err := <-errC; err == nil {
+//		t.Fatal("expected a timeout")
+//	}
+package arptest
+
+import (
+	"net"
+	"time"
+
+	arp "github.com/pefish/go-arping"
+)
+
+// NewFakeClient creates an arp.Client backed by a new Conn, on the fake
+// interface "arptest0" with the hardware address 02:00:00:00:00:01 and the
+// IPv4 address 192.0.2.1. The Client uses the Conn's Clock, which starts at
+// the current time. opts are applied after the options selecting that
+// address and Clock; QueueReply always addresses 192.0.2.1.
+//
+// Context deadlines are wall clock times, which the Clock only matches
+// until it is first advanced, so prefer canceling contexts explicitly in
+// tests using the Clock.
+func NewFakeClient(opts ...arp.Option) (*arp.Client, *Conn, error) {
+	ifi := &net.Interface{
+		Index:        1,
+		Name:         "arptest0",
+		MTU:          1500,
+		HardwareAddr: net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01},
+		Flags:        net.FlagUp | net.FlagBroadcast,
+	}
+	ip := net.IPv4(192, 0, 2, 1).To4()
+	clock := NewClock(time.Now())
+
+	conn := NewConn(ifi, ip, clock)
+	opts = append([]arp.Option{
+		arp.WithSourceIP(ip),
+		arp.WithClock(clock),
+	}, opts...)
+
+	c, err := arp.New(ifi, conn, opts...)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	return c, conn, nil
+}
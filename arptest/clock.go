@@ -0,0 +1,103 @@
+package arptest
+
+import (
+	"sync"
+	"time"
+
+	arp "github.com/pefish/go-arping"
+)
+
+var _ arp.Clock = (*Clock)(nil)
+
+// A Clock is a fake arp.Clock whose time only moves when Advance is called.
+// It also drives the read deadlines of the Conns created with it.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []timer
+	conns  map[*Conn]struct{}
+}
+
+// A timer is a pending channel returned by Clock.After.
+type timer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewClock creates a Clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now:   now,
+		conns: make(map[*Conn]struct{}),
+	}
+}
+
+// Now returns the current time of the Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel which receives the current time of the Clock
+// once it has been advanced by at least d. If d is not positive, the
+// channel receives immediately.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := timer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t.c
+	}
+
+	c.timers = append(c.timers, t)
+	return t.c
+}
+
+// Advance moves the Clock forward by d. It fires the channels returned by
+// After which are due, and times out reads of its Conns whose deadline has
+// passed.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		t.c <- now
+	}
+	c.timers = pending
+
+	conns := make([]*Conn, 0, len(c.conns))
+	for conn := range c.conns {
+		conns = append(conns, conn)
+	}
+	c.mu.Unlock()
+
+	// Wake up the Conns without holding the lock, since they call Now
+	// under their own lock.
+	for _, conn := range conns {
+		conn.wake()
+	}
+}
+
+// register and unregister add and remove conn from the Conns woken up by
+// Advance.
+func (c *Clock) register(conn *Conn) {
+	c.mu.Lock()
+	c.conns[conn] = struct{}{}
+	c.mu.Unlock()
+}
+
+func (c *Clock) unregister(conn *Conn) {
+	c.mu.Lock()
+	delete(c.conns, conn)
+	c.mu.Unlock()
+}
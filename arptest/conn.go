@@ -0,0 +1,241 @@
+package arptest
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+	"github.com/pefish/go-net-raw"
+)
+
+var _ net.PacketConn = (*Conn)(nil)
+
+// errClosed is returned by a Conn after Close.
+var errClosed = errors.New("arptest: connection closed")
+
+// timeoutError is returned by a Conn when its read deadline has passed.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// A Conn is a fake net.PacketConn for a Client, which reads the frames
+// queued with Queue and QueueReply, and records the frames written to it.
+//
+// Like a socket, ReadFrom blocks until a frame is available, the read
+// deadline passes, or the Conn is closed. Deadlines are measured against
+// the Conn's Clock, so a read only times out once the Clock is advanced
+// past its deadline. Writes never block. A Conn is safe for concurrent use.
+type Conn struct {
+	ifi   *net.Interface
+	ip    net.IP
+	clock *Clock
+
+	// mu guards the fields below. cond is signaled whenever one of them
+	// changes, or the Clock is advanced.
+	mu       sync.Mutex
+	cond     *sync.Cond
+	in       [][]byte
+	out      [][]byte
+	deadline time.Time
+	waiting  int
+	closed   bool
+}
+
+// NewConn creates a Conn for a Client on ifi with the IPv4 address ip,
+// whose deadlines are measured against clock.
+func NewConn(ifi *net.Interface, ip net.IP, clock *Clock) *Conn {
+	c := &Conn{
+		ifi:   ifi,
+		ip:    ip,
+		clock: clock,
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	clock.register(c)
+	return c
+}
+
+// Clock returns the Clock against which the Conn's deadlines are measured.
+func (c *Conn) Clock() *Clock { return c.clock }
+
+// Queue queues the ethernet frame b to be read by the Client. b is copied.
+func (c *Conn) Queue(b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.in = append(c.in, append([]byte(nil), b...))
+	c.cond.Broadcast()
+}
+
+// QueueReply queues an ARP reply from the host with the IPv4 address ip
+// and the hardware address mac, addressed to the Client's interface, as if
+// that host answered a request from the Client.
+func (c *Conn) QueueReply(ip net.IP, mac net.HardwareAddr) error {
+	p, err := net_arp.NewPacket(net_arp.OperationReply, mac, ip, c.ifi.HardwareAddr, c.ip)
+	if err != nil {
+		return err
+	}
+
+	pb, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	f := &ethernet.Frame{
+		Destination: c.ifi.HardwareAddr,
+		Source:      mac,
+		EtherType:   ethernet.EtherTypeARP,
+		Payload:     pb,
+	}
+	fb, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	c.Queue(fb)
+	return nil
+}
+
+// Written returns copies of the ethernet frames written by the Client so
+// far, in order.
+func (c *Conn) Written() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([][]byte, 0, len(c.out))
+	for _, b := range c.out {
+		out = append(out, append([]byte(nil), b...))
+	}
+	return out
+}
+
+// WrittenPackets returns the ARP packets among the frames written by the
+// Client so far, in order. Frames which do not carry an ARP packet are
+// skipped.
+func (c *Conn) WrittenPackets() []*net_arp.Packet {
+	var ps []*net_arp.Packet
+	for _, b := range c.Written() {
+		var f ethernet.Frame
+		if err := f.UnmarshalBinary(b); err != nil || f.EtherType != ethernet.EtherTypeARP {
+			continue
+		}
+
+		p := new(net_arp.Packet)
+		if err := p.UnmarshalBinary(f.Payload); err != nil {
+			continue
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// WaitRead blocks until a ReadFrom call is waiting for a frame. It allows
+// a test to advance the Clock only once an operation running in another
+// goroutine has set its read deadline and started reading.
+func (c *Conn) WaitRead() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.waiting == 0 && !c.closed {
+		c.cond.Wait()
+	}
+}
+
+// wake interrupts pending reads, so they check their deadline again.
+func (c *Conn) wake() {
+	c.mu.Lock()
+	c.cond.Broadcast()
+	c.mu.Unlock()
+}
+
+// ReadFrom implements net.PacketConn. The returned address is the source
+// hardware address of the frame, as a *raw.Addr.
+func (c *Conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		switch {
+		case c.closed:
+			return 0, nil, errClosed
+		case len(c.in) > 0:
+			f := c.in[0]
+			c.in = c.in[1:]
+
+			var addr net.Addr
+			if len(f) >= 12 {
+				addr = &net_raw.Addr{HardwareAddr: append(net.HardwareAddr(nil), f[6:12]...)}
+			}
+			return copy(b, f), addr, nil
+		case !c.deadline.IsZero() && !c.clock.Now().Before(c.deadline):
+			return 0, nil, timeoutError{}
+		}
+
+		c.waiting++
+		c.cond.Broadcast()
+		c.cond.Wait()
+		c.waiting--
+	}
+}
+
+// WriteTo implements net.PacketConn, recording the frame b.
+func (c *Conn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, errClosed
+	}
+
+	c.out = append(c.out, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+// Close implements net.PacketConn. Pending and future reads and writes
+// fail, while the written frames remain available.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errClosed
+	}
+
+	c.closed = true
+	c.cond.Broadcast()
+	c.clock.unregister(c)
+	return nil
+}
+
+// LocalAddr implements net.PacketConn, returning the hardware address of
+// the interface as a *raw.Addr.
+func (c *Conn) LocalAddr() net.Addr {
+	return &net_raw.Addr{HardwareAddr: c.ifi.HardwareAddr}
+}
+
+// SetDeadline implements net.PacketConn. Writes never block, so only the
+// read deadline is set.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements net.PacketConn.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = t
+	c.cond.Broadcast()
+	return nil
+}
+
+// SetWriteDeadline implements net.PacketConn. Writes never block, so it
+// has no effect.
+func (c *Conn) SetWriteDeadline(time.Time) error {
+	return nil
+}
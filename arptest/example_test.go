@@ -0,0 +1,44 @@
+package arptest_test
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	arp "github.com/pefish/go-arping"
+	"github.com/pefish/go-arping/arptest"
+)
+
+// This example resolves an address against a fake host, and lets the
+// cached binding expire by advancing the fake Clock.
+func Example() {
+	c, conn, err := arptest.NewFakeClient()
+	if err != nil {
+		log.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ip := net.IPv4(192, 0, 2, 2)
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	if err := conn.QueueReply(ip, mac); err != nil {
+		log.Fatalf("failed to queue reply: %v", err)
+	}
+
+	got, err := c.ResolveCached(ip)
+	if err != nil {
+		log.Fatalf("failed to resolve: %v", err)
+	}
+	fmt.Printf("resolved %s to %s with %d request\n", ip, got, len(conn.WrittenPackets()))
+
+	_, ok := c.Cache().Lookup(ip)
+	fmt.Println("cached:", ok)
+
+	conn.Clock().Advance(arp.DefaultCacheTTL)
+	_, ok = c.Cache().Lookup(ip)
+	fmt.Println("cached after the TTL:", ok)
+
+	// Output:
+	// resolved 192.0.2.2 to 02:00:00:00:00:02 with 1 request
+	// cached: true
+	// cached after the TTL: false
+}
@@ -0,0 +1,105 @@
+package arp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+	"golang.org/x/net/bpf"
+)
+
+// errEmptyBPFFilter is returned when an empty BPF program is configured.
+var errEmptyBPFFilter = errors.New("BPF filter must contain at least one instruction")
+
+// Offsets of the fields of an untagged ethernet frame carrying an ARP
+// packet for Ethernet and IPv4, which the prebuilt filters inspect.
+const (
+	bpfOffEtherType = 12
+	bpfOffAddrLens  = 18
+	bpfOffSenderIP  = 28
+	bpfOffTargetIP  = 38
+
+	// bpfAccept is returned by a filter to keep a whole frame.
+	bpfAccept = 0xffffffff
+)
+
+// WithBPFFilter attaches the classic BPF program filter to the socket, so
+// the kernel discards unwanted frames before they are copied to the
+// process. On busy segments, this saves most of the CPU time a monitoring
+// Client would otherwise spend reading and discarding frames.
+//
+// The filter is attached with SO_ATTACH_FILTER on Linux, and with the BPF
+// device ioctls on BSD. On Linux, Dial attaches it when the socket is
+// created, before the socket is bound, so no unfiltered frames are read.
+// It applies as well to every other net.PacketConn which implements
+// bpf.Setter, such as one passed to New, although frames received before
+// it is attached may still be read. Otherwise, or if attaching fails, a
+// warning is written to the Logger, see Capabilities. The program sees
+// frames as delivered by the socket: Linux usually strips the VLAN tag of
+// received frames, so offsets are those of an untagged frame. See
+// ARPFilter and ARPIPFilter for prebuilt programs.
+func WithBPFFilter(filter []bpf.RawInstruction) Option {
+	return func(cfg *config) error {
+		if len(filter) == 0 {
+			return errEmptyBPFFilter
+		}
+
+		cfg.bpfFilter = append([]bpf.RawInstruction(nil), filter...)
+		return nil
+	}
+}
+
+// ARPFilter returns a BPF program for WithBPFFilter which only accepts
+// untagged ARP frames, for use with the default ARP EtherType.
+func ARPFilter() []bpf.RawInstruction {
+	return mustAssemble([]bpf.Instruction{
+		// Accept the frame if its EtherType is ARP.
+		bpf.LoadAbsolute{Off: bpfOffEtherType, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: uint32(ethernet.EtherTypeARP), SkipTrue: 1},
+		bpf.RetConstant{Val: bpfAccept},
+		bpf.RetConstant{Val: 0},
+	})
+}
+
+// ARPIPFilter returns a BPF program for WithBPFFilter which only accepts
+// untagged ARP frames for Ethernet and IPv4 which carry ip as their sender
+// or target address, such as requests for ip and replies from ip. ip must
+// be an IPv4 address.
+func ARPIPFilter(ip net.IP) ([]bpf.RawInstruction, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, net_arp.ErrInvalidIP
+	}
+	val := binary.BigEndian.Uint32(ip4)
+
+	return mustAssemble([]bpf.Instruction{
+		// Drop the frame unless its EtherType is ARP.
+		bpf.LoadAbsolute{Off: bpfOffEtherType, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: uint32(ethernet.EtherTypeARP), SkipTrue: 7},
+
+		// Drop the frame unless it uses 6 byte hardware and 4 byte
+		// protocol addresses, so the addresses are at fixed offsets.
+		bpf.LoadAbsolute{Off: bpfOffAddrLens, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: ethernetAddrLen<<8 | net.IPv4len, SkipTrue: 5},
+
+		// Accept the frame if either address is ip.
+		bpf.LoadAbsolute{Off: bpfOffSenderIP, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: val, SkipTrue: 2},
+		bpf.LoadAbsolute{Off: bpfOffTargetIP, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: val, SkipTrue: 1},
+		bpf.RetConstant{Val: bpfAccept},
+		bpf.RetConstant{Val: 0},
+	}), nil
+}
+
+// mustAssemble assembles prog, which is a valid program built by this
+// package.
+func mustAssemble(prog []bpf.Instruction) []bpf.RawInstruction {
+	raw, err := bpf.Assemble(prog)
+	if err != nil {
+		panic("arp: invalid BPF program: " + err.Error())
+	}
+	return raw
+}
@@ -0,0 +1,168 @@
+package arp
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+	"golang.org/x/net/bpf"
+)
+
+func TestBPFFilters(t *testing.T) {
+	otherIP := net.IPv4(192, 0, 2, 3).To4()
+
+	request := arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, ethernet.BroadcastHardwareAddr, testIP)
+	reply := arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+	other := arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, ethernet.BroadcastHardwareAddr, otherIP)
+
+	tagged, err := (&ethernet.Frame{
+		Destination: ethernet.BroadcastHardwareAddr,
+		Source:      peerMAC,
+		VLAN:        &ethernet.VLAN{ID: 10},
+		EtherType:   ethernet.EtherTypeARP,
+		Payload:     request[headerLen:],
+	}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal tagged frame: %v", err)
+	}
+
+	// An IPv4 header whose addresses would match at the ARP offsets.
+	ipv4, err := (&ethernet.Frame{
+		Destination: testMAC,
+		Source:      peerMAC,
+		EtherType:   ethernet.EtherTypeIPv4,
+		Payload:     request[headerLen:],
+	}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal IPv4 frame: %v", err)
+	}
+
+	ipFilter, err := ARPIPFilter(testIP)
+	if err != nil {
+		t.Fatalf("failed to create ARPIPFilter: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter []bpf.RawInstruction
+		frame  []byte
+		ok     bool
+	}{
+		{name: "ARPFilter request", filter: ARPFilter(), frame: request, ok: true},
+		{name: "ARPFilter reply", filter: ARPFilter(), frame: reply, ok: true},
+		{name: "ARPFilter other target", filter: ARPFilter(), frame: other, ok: true},
+		{name: "ARPFilter VLAN tagged", filter: ARPFilter(), frame: tagged},
+		{name: "ARPFilter IPv4", filter: ARPFilter(), frame: ipv4},
+		{name: "ARPIPFilter request for IP", filter: ipFilter, frame: request, ok: true},
+		{name: "ARPIPFilter reply to IP", filter: ipFilter, frame: reply, ok: true},
+		{name: "ARPIPFilter wrong target IP", filter: ipFilter, frame: other},
+		{name: "ARPIPFilter VLAN tagged", filter: ipFilter, frame: tagged},
+		{name: "ARPIPFilter IPv4", filter: ipFilter, frame: ipv4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, ok := bpf.Disassemble(tt.filter)
+			if !ok {
+				t.Fatal("failed to disassemble filter")
+			}
+			vm, err := bpf.NewVM(prog)
+			if err != nil {
+				t.Fatalf("failed to create VM: %v", err)
+			}
+
+			n, err := vm.Run(tt.frame)
+			if err != nil {
+				t.Fatalf("failed to run filter: %v", err)
+			}
+
+			// Like the kernel, keep at most the whole frame.
+			if n > len(tt.frame) {
+				n = len(tt.frame)
+			}
+
+			want := 0
+			if tt.ok {
+				want = len(tt.frame)
+			}
+			if n != want {
+				t.Fatalf("filter kept %d bytes, want %d", n, want)
+			}
+		})
+	}
+}
+
+func TestARPIPFilterInvalidIP(t *testing.T) {
+	if _, err := ARPIPFilter(net.ParseIP("2001:db8::1")); err == nil {
+		t.Fatal("expected an error for an IPv6 address")
+	}
+}
+
+// bpfConn is a testConn which records the BPF programs attached to it.
+type bpfConn struct {
+	*testConn
+	attached int
+}
+
+func (c *bpfConn) SetBPF([]bpf.RawInstruction) error {
+	c.attached++
+	return nil
+}
+
+func TestInitFeaturesBPFFilter(t *testing.T) {
+	ifi := &net.Interface{Name: "test0"}
+	cfg, err := newConfig([]Option{
+		WithLogger(log.New(ioutil.Discard, "", 0)),
+		WithBPFFilter(ARPFilter()),
+	})
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filtered bool
+		setter   bool
+		attached int
+		caps     bool
+	}{
+		{
+			name:     "attached at creation",
+			filtered: true,
+			setter:   true,
+			caps:     true,
+		},
+		{
+			name:     "attached by initFeatures",
+			setter:   true,
+			attached: 1,
+			caps:     true,
+		},
+		{
+			name: "unsupported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := &bpfConn{testConn: newTestConn()}
+			defer bc.Close()
+
+			var p net.PacketConn = bc.testConn
+			if tt.setter {
+				p = bc
+			}
+
+			caps := initFeatures(ifi, p, cfg, tt.filtered)
+			if caps.BPFFilter != tt.caps {
+				t.Fatalf("unexpected BPFFilter capability: want %v, got %v", tt.caps, caps.BPFFilter)
+			}
+			if bc.attached != tt.attached {
+				t.Fatalf("unexpected number of attached filters: want %d, got %d", tt.attached, bc.attached)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is the TTL of the Cache created for each Client, unless
+// changed with WithCacheTTL.
+const DefaultCacheTTL = 5 * time.Minute
+
+// A Binding is an association between an IPv4 address and a hardware
+// address.
+type Binding struct {
+	IP           net.IP
+	HardwareAddr net.HardwareAddr
+
+	// Seen is the last time the binding was observed.
+	Seen time.Time
+}
+
+// A Cache is a table of IPv4 to hardware address bindings, which is safe
+// for concurrent use. Each Client has a Cache, see Client.Cache, whose
+// expiry is measured on the Client's Clock.
+type Cache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu         sync.Mutex
+	maxAge     time.Duration
+	entries    map[string]*cacheEntry
+	onConflict func(ip net.IP, old, new net.HardwareAddr) bool
+}
+
+// A cacheEntry is a binding stored in a Cache.
+type cacheEntry struct {
+	b Binding
+
+	// touched is the last time the entry was stored or looked up, and
+	// determines its expiry.
+	touched time.Time
+}
+
+// NewCache creates an empty Cache. Entries which are neither stored nor
+// looked up for ttl expire. A ttl of zero means entries never expire.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		clock:   systemClock{},
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// Lookup returns the hardware address bound to ip, if an unexpired entry
+// exists. A successful Lookup refreshes the entry's expiry.
+func (c *Cache) Lookup(ip net.IP) (net.HardwareAddr, bool) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.live(ip, now)
+	if !ok {
+		return nil, false
+	}
+
+	e.touched = now
+	return e.b.HardwareAddr, true
+}
+
+// Store binds ip to mac, observed at time seen. IPv4 addresses are stored
+// in their 4 byte form. Store reports whether the
+// binding is new, or replaced a different hardware address. Storing an
+// existing binding only updates its timestamps. If ip is bound to a
+// different hardware address, the conflict handler set with
+// SetConflictHandler decides whether it is replaced.
+func (c *Cache) Store(ip net.IP, mac net.HardwareAddr, seen time.Time) bool {
+	ip = normalizeIP(ip)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.live(ip, seen); ok {
+		if bytes.Equal(e.b.HardwareAddr, mac) {
+			e.b.Seen = seen
+			e.touched = seen
+			return false
+		}
+
+		if c.onConflict != nil && !c.onConflict(ip, e.b.HardwareAddr, mac) {
+			return false
+		}
+	}
+
+	c.entries[ip.String()] = &cacheEntry{
+		b: Binding{
+			IP:           ip,
+			HardwareAddr: mac,
+			Seen:         seen,
+		},
+		touched: seen,
+	}
+	return true
+}
+
+// SetMaxAge sets the maximum age of bindings: a binding which was last
+// stored more than d ago expires, even if it is looked up constantly. This
+// prevents serving a hardware address which silently changed on the
+// network, since callers such as ResolveCached then resolve it again. Zero,
+// the default, means bindings only expire once idle for the TTL.
+//
+// The TTL is an idle expiry, which Lookup and Store both refresh, while the
+// maximum age is an absolute expiry, which only Store refreshes, using the
+// time the binding was seen. A binding which exceeded it no longer
+// conflicts with a new one, so the conflict handler is not called when it
+// is replaced.
+func (c *Cache) SetMaxAge(d time.Duration) {
+	c.mu.Lock()
+	c.maxAge = d
+	c.mu.Unlock()
+}
+
+// SetConflictHandler sets a function which is called by Store before an
+// unexpired binding of ip to old is replaced with new. If fn returns false,
+// the old binding is kept, which allows implementing first-wins or
+// alerting policies, since a changing binding may indicate ARP spoofing. A
+// nil fn restores the default, last-wins behavior.
+//
+// fn is called with the Cache locked, so it must not use the Cache.
+func (c *Cache) SetConflictHandler(fn func(ip net.IP, old, new net.HardwareAddr) bool) {
+	c.mu.Lock()
+	c.onConflict = fn
+	c.mu.Unlock()
+}
+
+// Delete removes any binding for ip.
+func (c *Cache) Delete(ip net.IP) {
+	c.mu.Lock()
+	delete(c.entries, ip.String())
+	c.mu.Unlock()
+}
+
+// Bindings returns all unexpired bindings, sorted by IPv4 address.
+func (c *Cache) Bindings() []Binding {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	bs := make([]Binding, 0, len(c.entries))
+	for k, e := range c.entries {
+		if c.expired(e, now) {
+			delete(c.entries, k)
+			continue
+		}
+		bs = append(bs, e.b)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(bs, func(i, j int) bool {
+		return bytes.Compare(bs[i].IP.To16(), bs[j].IP.To16()) < 0
+	})
+	return bs
+}
+
+// live returns the unexpired entry for ip, removing it if it has expired.
+// The caller must hold c.mu.
+func (c *Cache) live(ip net.IP, now time.Time) (*cacheEntry, bool) {
+	k := ip.String()
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+
+	if c.expired(e, now) {
+		delete(c.entries, k)
+		return nil, false
+	}
+
+	return e, true
+}
+
+// expired reports whether e has expired at now, either because it was idle
+// for the TTL, or because it exceeded the maximum age. The caller must hold
+// c.mu.
+func (c *Cache) expired(e *cacheEntry, now time.Time) bool {
+	if c.maxAge > 0 && now.Sub(e.b.Seen) >= c.maxAge {
+		return true
+	}
+	return c.ttl > 0 && now.Sub(e.touched) >= c.ttl
+}
+
+// Cache returns the Client's Cache.
+func (c *Client) Cache() *Cache {
+	return c.cache
+}
@@ -0,0 +1,225 @@
+package arp
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// firstWins is a conflict handler which keeps the existing binding.
+func firstWins(net.IP, net.HardwareAddr, net.HardwareAddr) bool { return false }
+
+func TestCacheConflictHandler(t *testing.T) {
+	peer2MAC := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x03}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		handler func(ip net.IP, old, new net.HardwareAddr) bool
+		// oldSeen is when the binding of peerIP to peerMAC was stored
+		// before peer2MAC is stored.
+		oldSeen time.Time
+		second  net.HardwareAddr
+		// called reports whether the handler must be called, replaced
+		// what Store must return, and want the resulting binding.
+		called   bool
+		replaced bool
+		want     net.HardwareAddr
+	}{
+		{
+			name:     "last wins by default",
+			oldSeen:  now,
+			second:   peer2MAC,
+			replaced: true,
+			want:     peer2MAC,
+		},
+		{
+			name:     "handler accepts",
+			handler:  func(net.IP, net.HardwareAddr, net.HardwareAddr) bool { return true },
+			oldSeen:  now,
+			second:   peer2MAC,
+			called:   true,
+			replaced: true,
+			want:     peer2MAC,
+		},
+		{
+			name:    "handler rejects",
+			handler: firstWins,
+			oldSeen: now,
+			second:  peer2MAC,
+			called:  true,
+			want:    peerMAC,
+		},
+		{
+			name:    "same binding",
+			handler: firstWins,
+			oldSeen: now,
+			second:  peerMAC,
+			want:    peerMAC,
+		},
+		{
+			name:     "expired binding",
+			handler:  firstWins,
+			oldSeen:  now.Add(-2 * time.Minute),
+			second:   peer2MAC,
+			replaced: true,
+			want:     peer2MAC,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache(time.Minute)
+
+			var called bool
+			if tt.handler != nil {
+				c.SetConflictHandler(func(ip net.IP, old, new net.HardwareAddr) bool {
+					called = true
+					if !ip.Equal(peerIP) || len(ip) != net.IPv4len {
+						t.Fatalf("unexpected conflicting IP: %v", []byte(ip))
+					}
+					if !bytes.Equal(old, peerMAC) || !bytes.Equal(new, tt.second) {
+						t.Fatalf("unexpected conflict: %s -> %s", old, new)
+					}
+					return tt.handler(ip, old, new)
+				})
+			}
+
+			if !c.Store(peerIP.To16(), peerMAC, tt.oldSeen) {
+				t.Fatal("first binding was not new")
+			}
+			if got := c.Store(peerIP, tt.second, now); got != tt.replaced {
+				t.Fatalf("unexpected Store result: want %v, got %v", tt.replaced, got)
+			}
+			if called != tt.called {
+				t.Fatalf("unexpected handler call: want %v, got %v", tt.called, called)
+			}
+
+			mac, ok := c.Lookup(peerIP)
+			if !ok || !bytes.Equal(tt.want, mac) {
+				t.Fatalf("unexpected binding: want %s, got %s (%v)", tt.want, mac, ok)
+			}
+		})
+	}
+
+	t.Run("nil handler restores last wins", func(t *testing.T) {
+		c := NewCache(0)
+		c.SetConflictHandler(firstWins)
+		c.SetConflictHandler(nil)
+
+		c.Store(peerIP, peerMAC, now)
+		if !c.Store(peerIP, peer2MAC, now) {
+			t.Fatal("binding was not replaced")
+		}
+	})
+
+	t.Run("WithCacheConflict", func(t *testing.T) {
+		c, _ := testClient(t, WithCacheConflict(firstWins))
+		defer c.Close()
+
+		c.Cache().Store(peerIP, peerMAC, now)
+		if c.Cache().Store(peerIP, peer2MAC, now) {
+			t.Fatal("Client's Cache does not use the conflict handler")
+		}
+	})
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		maxAge time.Duration
+		// stored are the times at which peerIP is bound to peerMAC, and
+		// ok reports whether the binding must be live afterwards.
+		stored []time.Time
+		ok     bool
+	}{
+		{
+			name:   "no maximum age",
+			stored: []time.Time{now.Add(-time.Hour)},
+			ok:     true,
+		},
+		{
+			name:   "within maximum age",
+			maxAge: time.Minute,
+			stored: []time.Time{now.Add(-30 * time.Second)},
+			ok:     true,
+		},
+		{
+			name:   "exceeds maximum age",
+			maxAge: time.Minute,
+			stored: []time.Time{now.Add(-2 * time.Minute)},
+		},
+		{
+			name:   "exceeds maximum age within TTL",
+			ttl:    time.Hour,
+			maxAge: time.Minute,
+			stored: []time.Time{now.Add(-2 * time.Minute)},
+		},
+		{
+			name:   "refreshed by Store",
+			maxAge: time.Minute,
+			stored: []time.Time{now.Add(-2 * time.Minute), now},
+			ok:     true,
+		},
+		{
+			name:   "idle for TTL within maximum age",
+			ttl:    time.Minute,
+			maxAge: time.Hour,
+			stored: []time.Time{now.Add(-2 * time.Minute)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache(tt.ttl)
+			c.SetMaxAge(tt.maxAge)
+
+			for _, seen := range tt.stored {
+				c.Store(peerIP, peerMAC, seen)
+			}
+
+			if _, ok := c.Lookup(peerIP); ok != tt.ok {
+				t.Fatalf("unexpected Lookup result: want %v, got %v", tt.ok, ok)
+			}
+			if n := len(c.Bindings()); (n == 1) != tt.ok {
+				t.Fatalf("unexpected number of bindings: %d", n)
+			}
+		})
+	}
+
+	t.Run("no conflict once aged out", func(t *testing.T) {
+		c := NewCache(0)
+		c.SetMaxAge(time.Minute)
+
+		var called bool
+		c.SetConflictHandler(func(net.IP, net.HardwareAddr, net.HardwareAddr) bool {
+			called = true
+			return false
+		})
+
+		c.Store(peerIP, peerMAC, now.Add(-2*time.Minute))
+		peer2MAC := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x03}
+		if !c.Store(peerIP, peer2MAC, now) || called {
+			t.Fatalf("aged out binding was not replaced without conflict: called %v", called)
+		}
+	})
+
+	t.Run("WithCacheMaxAge", func(t *testing.T) {
+		if _, err := newConfig([]Option{WithCacheMaxAge(-time.Second)}); !errors.Is(err, errInvalidCacheMaxAge) {
+			t.Fatalf("unexpected error for a negative maximum age: %v", err)
+		}
+
+		c, _ := testClient(t, WithCacheMaxAge(time.Minute))
+		defer c.Close()
+
+		c.Cache().Store(peerIP, peerMAC, now.Add(-2*time.Minute))
+		if _, ok := c.Cache().Lookup(peerIP); ok {
+			t.Fatal("Client's Cache does not use the maximum age")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package arp
+
+import (
+	"net"
+
+	"github.com/pefish/go-net-raw"
+	"golang.org/x/net/bpf"
+)
+
+// Capabilities reports which optional features were successfully
+// initialized for a Client. Tools can use it to degrade gracefully when a
+// platform, driver or net.PacketConn does not support a feature, instead
+// of failing to create the Client.
+type Capabilities struct {
+	// PacketPriority is true if the priority set with WithPacketPriority
+	// was applied to the socket.
+	PacketPriority bool
+
+	// Promiscuous is true if promiscuous mode was enabled with
+	// WithPromiscuous.
+	Promiscuous bool
+
+	// PacketStats is true if socket statistics can be retrieved with
+	// Stats.
+	PacketStats bool
+
+	// BPFFilter is true if the program set with WithBPFFilter was
+	// attached to the socket.
+	BPFFilter bool
+
+	// LinkMonitor is true if the link monitor required by options such as
+	// WithHardwareAddrChange and WithInterfaceDownError is running.
+	LinkMonitor bool
+}
+
+// A promiscuousSetter is a net.PacketConn which can enable promiscuous
+// mode, such as a net_raw.Conn.
+type promiscuousSetter interface {
+	SetPromiscuous(b bool) error
+}
+
+// A statser is a net.PacketConn which can report socket statistics, such
+// as a net_raw.Conn.
+type statser interface {
+	Stats() (*net_raw.Stats, error)
+}
+
+// initFeatures sets up the optional features requested in cfg on p, and
+// probes which of them are available. Features which fail to initialize
+// are reported to the Logger rather than failing the Client. filtered
+// reports whether the BPF filter was attached when p was created, in which
+// case initFeatures only reports it.
+func initFeatures(ifi *net.Interface, p net.PacketConn, cfg config, filtered bool) Capabilities {
+	var caps Capabilities
+
+	if cfg.priority != nil {
+		if err := setPriority(p, *cfg.priority); err != nil {
+			cfg.logger.Printf("%s: packet priority %d not applied: %v", ifi.Name, *cfg.priority, err)
+		} else {
+			caps.PacketPriority = true
+		}
+	}
+
+	if cfg.promiscuous {
+		if ps, ok := p.(promiscuousSetter); !ok {
+			cfg.logger.Printf("%s: promiscuous mode not enabled: not supported by %T", ifi.Name, p)
+		} else if err := ps.SetPromiscuous(true); err != nil {
+			cfg.logger.Printf("%s: promiscuous mode not enabled: %v", ifi.Name, err)
+		} else {
+			caps.Promiscuous = true
+		}
+	}
+
+	// Sockets created by Dial on Linux have the filter attached already;
+	// other connections, such as those passed to New, have it attached now.
+	if filtered {
+		caps.BPFFilter = true
+	} else if cfg.bpfFilter != nil {
+		if s, ok := p.(bpf.Setter); !ok {
+			cfg.logger.Printf("%s: BPF filter not attached: not supported by %T", ifi.Name, p)
+		} else if err := s.SetBPF(cfg.bpfFilter); err != nil {
+			cfg.logger.Printf("%s: BPF filter not attached: %v", ifi.Name, err)
+		} else {
+			caps.BPFFilter = true
+		}
+	}
+
+	if s, ok := p.(statser); ok {
+		if _, err := s.Stats(); err == nil {
+			caps.PacketStats = true
+		}
+	}
+
+	return caps
+}
+
+// Capabilities returns the optional features which were successfully
+// initialized when the Client was created.
+func (c *Client) Capabilities() Capabilities {
+	return c.caps
+}
+
+// Stats retrieves packet statistics from the Client's socket. It returns
+// net_raw.ErrNotImplemented if the socket does not support statistics, see
+// Capabilities.
+func (c *Client) Stats() (*net_raw.Stats, error) {
+	s, ok := c.p.(statser)
+	if !ok {
+		return nil, c.opError("stats", net_raw.ErrNotImplemented)
+	}
+
+	st, err := s.Stats()
+	if err != nil {
+		return nil, c.opError("stats", err)
+	}
+	return st, nil
+}
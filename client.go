@@ -5,7 +5,9 @@ import (
 	"github.com/pefish/go-ethernet"
 	"github.com/pefish/go-net-arp"
 	"github.com/pefish/go-net-raw"
+	"math/rand"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -19,25 +21,70 @@ var (
 // Resolution Protocol, RFC 826).
 const protocolARP = 0x0806
 
+// readBufferSize is the size of the buffer used to read each frame, which
+// is ample for ARP packets.
+const readBufferSize = 128
+
 // A Client is an ARP client, which can be used to send and receive
 // ARP packets.
 type Client struct {
 	ifi *net.Interface
 	ip  net.IP
 	p   net.PacketConn
+
+	// name is the interface name at the time the Client was created, used
+	// to annotate errors.
+	name string
+
+	cfg   config
+	caps  Capabilities
+	cache *Cache
+	mon   monitor
+	taps  taps
+
+	// scratch holds the values decoded by ReadFrame.
+	scratch frameScratch
+
+	// rmu guards rand, which is not safe for concurrent use.
+	rmu  sync.Mutex
+	rand *rand.Rand
+
+	// link is the link monitor, which is nil unless an option requires it.
+	link linkMonitor
+
+	// mu guards readDeadline, the read deadline most recently set by the
+	// caller, so it can be restored by methods which temporarily shorten it,
+	// mac, the hardware address used to send frames, and down, which is set
+	// while the link monitor reports the interface as down.
+	mu           sync.Mutex
+	readDeadline time.Time
+	mac          net.HardwareAddr
+	down         bool
 }
 
 // Dial creates a new Client using the specified network interface.
 // Dial retrieves the IPv4 address of the interface and binds a raw socket
-// to send and receive ARP packets.
-func Dial(ifi *net.Interface) (*Client, error) {
+// to send and receive ARP packets. Options may be passed to change the
+// Client's behavior.
+func Dial(ifi *net.Interface, opts ...Option) (*Client, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Open raw socket to send and receive ARP packets using ethernet frames
 	// we build ourselves.
-	p, err := net_raw.ListenPacket(ifi, protocolARP, nil)
+	p, filtered, err := listenPacket(ifi, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newFromConn(ifi, p, cfg, filtered)
 	if err != nil {
+		_ = p.Close()
 		return nil, err
 	}
-	return New(ifi, p)
+	return c, nil
 }
 
 // New creates a new Client using the specified network interface
@@ -45,38 +92,92 @@ func Dial(ifi *net.Interface) (*Client, error) {
 // net.PacketConn. This is most useful to define what protocol to pass to socket(7).
 //
 // In most cases, callers would be better off calling Dial.
-func New(ifi *net.Interface, p net.PacketConn) (*Client, error) {
-	// Check for usable IPv4 addresses for the Client
-	addrs, err := ifi.Addrs()
+func New(ifi *net.Interface, p net.PacketConn, opts ...Option) (*Client, error) {
+	cfg, err := newConfig(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return newClient(ifi, p, addrs)
+	return newFromConn(ifi, p, cfg, false)
+}
+
+// newFromConn applies socket-level options from cfg to p and creates a
+// Client using the addresses of ifi. filtered reports whether the BPF
+// filter of cfg was already attached when p was created.
+func newFromConn(ifi *net.Interface, p net.PacketConn, cfg config, filtered bool) (*Client, error) {
+	caps := initFeatures(ifi, p, cfg, filtered)
+
+	// Check for usable IPv4 addresses for the Client, unless the source
+	// address was provided explicitly.
+	var addrs []net.Addr
+	if cfg.sourceIP == nil {
+		var err error
+		addrs, err = ifi.Addrs()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := newClient(ifi, p, addrs, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.caps = caps
+	c.caps.LinkMonitor = c.startLinkMonitor()
+	return c, nil
 }
 
 // newClient is the internal, generic implementation of newClient.  It is used
 // to allow an arbitrary net.PacketConn to be used in a Client, so testing
 // is easier to accomplish.
-func newClient(ifi *net.Interface, p net.PacketConn, addrs []net.Addr) (*Client, error) {
-	ip, err := firstIPv4Addr(addrs)
-	if err != nil {
-		return nil, err
+func newClient(ifi *net.Interface, p net.PacketConn, addrs []net.Addr, cfg config) (*Client, error) {
+	ip := cfg.sourceIP
+	if ip == nil {
+		var err error
+		ip, err = firstIPv4Addr(addrs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := cfg.rand
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
 
+	cache := NewCache(cfg.cacheTTL)
+	cache.clock = cfg.clock
+	cache.SetMaxAge(cfg.cacheMaxAge)
+	cache.SetConflictHandler(cfg.onCacheConflict)
+
 	return &Client{
-		ifi: ifi,
-		ip:  ip,
-		p:   p,
+		ifi:   ifi,
+		ip:    ip,
+		p:     p,
+		name:  ifi.Name,
+		cfg:   cfg,
+		cache: cache,
+		rand:  r,
+		mac:   ifi.HardwareAddr,
 	}, nil
 }
 
 // Close closes the Client's raw socket and stops sending and receiving
 // ARP packets.
 func (c *Client) Close() error {
+	if c.link != nil {
+		_ = c.link.Close()
+	}
 	return c.p.Close()
 }
 
+// InterfaceName returns the name of the network interface the Client was
+// created with.
+func (c *Client) InterfaceName() string {
+	return c.name
+}
+
 // Request sends an ARP request, asking for the hardware address
 // associated with an IPv4 address. The response, if any, can be read
 // with the Read method.
@@ -85,35 +186,97 @@ func (c *Client) Close() error {
 // hardware address, Request allows sending many requests in a row,
 // retrieving the responses afterwards.
 func (c *Client) Request(ip net.IP) error {
-	if c.ip == nil {
-		return errNoIPv4Addr
+	arp, err := c.newRequest(ip)
+	if err != nil {
+		return c.opError("request", err)
 	}
+	return c.WriteTo(arp, ethernet.BroadcastHardwareAddr)
+}
 
-	// Create ARP packet for broadcast address to attempt to find the
-	// hardware address of the input IP address
-	arp, err := net_arp.NewPacket(net_arp.OperationRequest, c.ifi.HardwareAddr, c.ip, ethernet.BroadcastHardwareAddr, ip)
+// RequestVia sends an ARP request asking for the hardware address of ip,
+// like Request, but addresses the ethernet frame to nextHop instead of the
+// broadcast address.
+//
+// ARP is link-local, and a request is normally broadcast to every host on
+// the segment. RequestVia is an escape hatch for special topologies, such
+// as L2 overlays or SDN test rigs, where a request for a target outside
+// the local subnet must be steered towards a specific next hop. Replies,
+// if any, can be read with the Read method.
+func (c *Client) RequestVia(ip net.IP, nextHop net.HardwareAddr) error {
+	if len(nextHop) != ethernetAddrLen {
+		return c.opError("request", net_arp.ErrInvalidHardwareAddr)
+	}
+
+	arp, err := c.newRequest(ip)
 	if err != nil {
-		return err
+		return c.opError("request", err)
+	}
+	return c.WriteTo(arp, nextHop)
+}
+
+// requestFrom sends an ARP request asking for the hardware address of ip,
+// like Request, but with src as the sender IP address.
+func (c *Client) requestFrom(src, ip net.IP) error {
+	arp, err := c.newRequestFrom(src, ip)
+	if err != nil {
+		return c.opError("request", err)
 	}
 	return c.WriteTo(arp, ethernet.BroadcastHardwareAddr)
 }
 
+// newRequest creates an ARP request packet asking for the hardware address
+// of ip.
+func (c *Client) newRequest(ip net.IP) (*net_arp.Packet, error) {
+	if c.ip == nil {
+		return nil, errNoIPv4Addr
+	}
+
+	return c.newRequestFrom(c.ip, ip)
+}
+
+// newRequestFrom creates an ARP request packet asking for the hardware
+// address of ip, sent from src.
+func (c *Client) newRequestFrom(src, ip net.IP) (*net_arp.Packet, error) {
+	// Create ARP packet for broadcast address to attempt to find the
+	// hardware address of the input IP address
+	return net_arp.NewPacket(net_arp.OperationRequest, c.HardwareAddr(), src, ethernet.BroadcastHardwareAddr, ip)
+}
+
 // Read reads a single ARP packet and returns it, together with its
-// ethernet frame.
+// ethernet frame. Errors are returned as an *OpError.
 func (c *Client) Read() (*net_arp.Packet, *ethernet.Frame, error) {
-	buf := make([]byte, 128)
+	if err := c.checkLink(nil); err != nil {
+		return nil, nil, c.opError("read", err)
+	}
+
+	buf := make([]byte, readBufferSize)
 	for {
 		n, _, err := c.p.ReadFrom(buf)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, c.opError("read", c.checkLink(err))
 		}
+		b := truncate(buf, n)
+		c.tapFrame(c.cfg.clock.Now(), b, n)
 
-		p, eth, err := net_arp.ParsePacket(buf[:n])
-		if err != nil {
-			if err == net_arp.ErrInvalidARPPacket {
-				continue
+		eth := new(ethernet.Frame)
+		if err := eth.UnmarshalBinary(b); err != nil {
+			return nil, nil, c.opError("read", &frameError{err: err})
+		}
+
+		// Ignore frames from other VLANs, and frames which do not carry
+		// the configured ARP EtherType
+		if !c.cfg.matchVLAN(eth) || eth.EtherType != c.cfg.etherType {
+			continue
+		}
+
+		p := new(net_arp.Packet)
+		if err := p.UnmarshalBinary(eth.Payload); err != nil {
+			return nil, nil, c.opError("read", &frameError{err: err})
+		}
+		if c.cfg.strict {
+			if err := checkTrailing(p, eth.Payload); err != nil {
+				return nil, nil, c.opError("read", &frameError{err: err})
 			}
-			return nil, nil, err
 		}
 		return p, eth, nil
 	}
@@ -121,27 +284,60 @@ func (c *Client) Read() (*net_arp.Packet, *ethernet.Frame, error) {
 
 // WriteTo writes a single ARP packet to addr. Note that addr should,
 // but doesn't have to, match the target hardware address of the ARP
-// packet.
+// packet. Errors are returned as an *OpError.
 func (c *Client) WriteTo(p *net_arp.Packet, addr net.HardwareAddr) error {
+	fb, err := c.marshalFrame(p, addr)
+	if err != nil {
+		return c.opError("write", err)
+	}
+
+	if _, err := c.p.WriteTo(fb, &net_raw.Addr{HardwareAddr: addr}); err != nil {
+		return c.opError("write", err)
+	}
+	return nil
+}
+
+// marshalFrame marshals p into an ethernet frame addressed to addr.
+func (c *Client) marshalFrame(p *net_arp.Packet, addr net.HardwareAddr) ([]byte, error) {
 	pb, err := p.MarshalBinary()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	f := &ethernet.Frame{
 		Destination: addr,
 		Source:      p.SenderHardwareAddr,
-		EtherType:   ethernet.EtherTypeARP,
+		EtherType:   c.cfg.etherType,
 		Payload:     pb,
 	}
+	if c.cfg.sourceMAC != nil {
+		f.Source = c.cfg.sourceMAC
+	}
+	if err := c.checkMTU(f); err != nil {
+		return nil, err
+	}
+
+	return f.MarshalBinary()
+}
+
+// WriteFrame writes a single ethernet frame, such as one returned by
+// BuildReply, to its destination address. The frame is sent as is, so its
+// source address and EtherType are not changed. Errors are returned as an
+// *OpError.
+func (c *Client) WriteFrame(f *ethernet.Frame) error {
+	if err := c.checkMTU(f); err != nil {
+		return c.opError("write", err)
+	}
 
 	fb, err := f.MarshalBinary()
 	if err != nil {
-		return err
+		return c.opError("write", err)
 	}
 
-	_, err = c.p.WriteTo(fb, &net_raw.Addr{HardwareAddr: addr})
-	return err
+	if _, err := c.p.WriteTo(fb, &net_raw.Addr{HardwareAddr: f.Destination}); err != nil {
+		return c.opError("write", err)
+	}
+	return nil
 }
 
 // Reply constructs and sends a reply to an ARP request. On the ARP
@@ -149,14 +345,59 @@ func (c *Client) WriteTo(p *net_arp.Packet, addr net.HardwareAddr) error {
 // the ethernet layer, it will be sent to the actual remote address
 // from which the request was received.
 //
-// For more fine-grained control, use WriteTo to write a custom
-// response.
+// If a delay is set with WithReplyDelay, Reply waits for it before
+// sending.
+//
+// For more fine-grained control, use BuildReply to construct the reply
+// frame, or WriteTo to write a custom response.
 func (c *Client) Reply(req *net_arp.Packet, hwAddr net.HardwareAddr, ip net.IP) error {
-	p, err := net_arp.NewPacket(net_arp.OperationReply, hwAddr, ip, req.SenderHardwareAddr, req.SenderIP)
+	f, err := BuildReply(req, nil, hwAddr, ip)
 	if err != nil {
-		return err
+		return c.opError("reply", err)
+	}
+	f.EtherType = c.cfg.etherType
+
+	if d := c.cfg.replyDelay + c.randDuration(c.cfg.replyJitter); d > 0 {
+		<-c.cfg.clock.After(d)
+	}
+
+	return c.WriteFrame(f)
+}
+
+// BuildReply constructs the ethernet frame of a reply to the ARP request
+// req, announcing that ip is at mac, without sending it. The frame can be
+// inspected or modified, and then sent with WriteFrame or elsewhere.
+//
+// The ARP reply is addressed to the sender of req. If reqFrame, the frame
+// req was read from, is not nil, the reply frame is addressed to its
+// source address, and uses its EtherType and VLAN tags. Otherwise it is
+// addressed to the sender hardware address of req, and uses the ARP
+// EtherType.
+func BuildReply(req *net_arp.Packet, reqFrame *ethernet.Frame, mac net.HardwareAddr, ip net.IP) (*ethernet.Frame, error) {
+	p, err := net_arp.NewPacket(net_arp.OperationReply, mac, ip, req.SenderHardwareAddr, req.SenderIP)
+	if err != nil {
+		return nil, err
+	}
+
+	pb, err := p.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &ethernet.Frame{
+		Destination: req.SenderHardwareAddr,
+		Source:      mac,
+		EtherType:   ethernet.EtherTypeARP,
+		Payload:     pb,
+	}
+	if reqFrame != nil {
+		f.Destination = reqFrame.Source
+		f.EtherType = reqFrame.EtherType
+		f.ServiceVLAN = reqFrame.ServiceVLAN
+		f.VLAN = reqFrame.VLAN
 	}
-	return c.WriteTo(p, req.SenderHardwareAddr)
+
+	return f, nil
 }
 
 // Copyright (c) 2012 The Go Authors. All rights reserved.
@@ -170,7 +411,14 @@ func (c *Client) Reply(req *net_arp.Packet, hwAddr net.HardwareAddr, ip net.IP)
 // SetDeadline sets the read and write deadlines associated with the
 // connection.
 func (c *Client) SetDeadline(t time.Time) error {
-	return c.p.SetDeadline(t)
+	if err := c.p.SetDeadline(t); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return nil
 }
 
 // SetReadDeadline sets the deadline for future raw socket read calls.
@@ -178,7 +426,14 @@ func (c *Client) SetDeadline(t time.Time) error {
 // (see type net.Error) instead of blocking.
 // A zero value for t means a raw socket read will not time out.
 func (c *Client) SetReadDeadline(t time.Time) error {
-	return c.p.SetReadDeadline(t)
+	if err := c.p.SetReadDeadline(t); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return nil
 }
 
 // SetWriteDeadline sets the deadline for future raw socket write calls.
@@ -192,9 +447,24 @@ func (c *Client) SetWriteDeadline(t time.Time) error {
 }
 
 // HardwareAddr fetches the hardware address for the interface associated
-// with the connection.
-func (c Client) HardwareAddr() net.HardwareAddr {
-	return c.ifi.HardwareAddr
+// with the connection. It is the address the interface had when the Client
+// was created, unless WithHardwareAddrAutoUpdate is used.
+func (c *Client) HardwareAddr() net.HardwareAddr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mac
+}
+
+// randDuration returns a random duration in [0, max), using the Client's
+// random source. It returns zero if max is not positive.
+func (c *Client) randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	return time.Duration(c.rand.Int63n(int64(max)))
 }
 
 // firstIPv4Addr attempts to retrieve the first detected IPv4 address from an
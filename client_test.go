@@ -0,0 +1,234 @@
+package arp
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+	"github.com/pefish/go-net-raw"
+)
+
+var (
+	testMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	testIP  = net.IPv4(192, 0, 2, 1).To4()
+
+	peerMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	peerIP  = net.IPv4(192, 0, 2, 2).To4()
+)
+
+// hostIP returns the address 192.0.2.n.
+func hostIP(n byte) net.IP {
+	return net.IPv4(192, 0, 2, n).To4()
+}
+
+// testClient creates a Client with opts on a testConn, with the hardware
+// address testMAC and the IPv4 address testIP.
+func testClient(t *testing.T, opts ...Option) (*Client, *testConn) {
+	t.Helper()
+
+	conn := newTestConn()
+
+	ifi := &net.Interface{
+		Index:        1,
+		Name:         "test0",
+		MTU:          1500,
+		HardwareAddr: testMAC,
+		Flags:        net.FlagUp | net.FlagBroadcast,
+	}
+	addrs := []net.Addr{&net.IPNet{
+		IP:   testIP,
+		Mask: net.CIDRMask(24, 32),
+	}}
+
+	opts = append([]Option{WithLogger(log.New(ioutil.Discard, "", 0))}, opts...)
+	cfg, err := newConfig(opts)
+	if err != nil {
+		t.Fatalf("failed to apply options: %v", err)
+	}
+
+	c, err := newClient(ifi, conn, addrs, cfg)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c, conn
+}
+
+// A testConn is an in-memory net.PacketConn. Frames sent on in are read by
+// the Client, and frames written by the Client are sent on out, or dropped
+// once out is full. Read deadlines are honored as by a socket.
+type testConn struct {
+	in, out chan []byte
+
+	// mu guards deadline and wake, which is closed to interrupt a pending
+	// read when the deadline changes.
+	mu       sync.Mutex
+	deadline time.Time
+	wake     chan struct{}
+
+	closed chan struct{}
+	once   sync.Once
+
+	// reportLen makes ReadFrom return the full length of each frame, even
+	// when it was truncated to fit the buffer, as a packet socket does
+	// with MSG_TRUNC.
+	reportLen bool
+}
+
+func newTestConn() *testConn {
+	return &testConn{
+		in:     make(chan []byte, 1024),
+		out:    make(chan []byte, 1024),
+		wake:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+// errTestConnClosed is returned by a testConn after Close.
+var errTestConnClosed = errors.New("test connection closed")
+
+// testTimeoutError is returned by a testConn when its read deadline passes.
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "i/o timeout" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+func (c *testConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mu.Lock()
+		deadline, wake := c.deadline, c.wake
+		c.mu.Unlock()
+
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, nil, testTimeoutError{}
+			}
+			expired = time.After(d)
+		}
+
+		select {
+		case f := <-c.in:
+			addr := &net_raw.Addr{}
+			if len(f) >= 12 {
+				addr.HardwareAddr = net.HardwareAddr(append([]byte(nil), f[6:12]...))
+			}
+			n := copy(b, f)
+			if c.reportLen {
+				n = len(f)
+			}
+			return n, addr, nil
+		case <-expired:
+			return 0, nil, testTimeoutError{}
+		case <-wake:
+			// The deadline changed, so wait again.
+		case <-c.closed:
+			return 0, nil, errTestConnClosed
+		}
+	}
+}
+
+func (c *testConn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, errTestConnClosed
+	default:
+	}
+
+	select {
+	case c.out <- append([]byte(nil), b...):
+	default:
+	}
+	return len(b), nil
+}
+
+func (c *testConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *testConn) LocalAddr() net.Addr { return &net_raw.Addr{} }
+
+func (c *testConn) SetDeadline(t time.Time) error { return c.SetReadDeadline(t) }
+
+func (c *testConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	close(c.wake)
+	c.wake = make(chan struct{})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *testConn) SetWriteDeadline(time.Time) error { return nil }
+
+// readDeadline returns the read deadline currently set on c.
+func (c *testConn) readDeadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline
+}
+
+// arpFrame returns an ethernet frame carrying an ARP packet with the
+// specified operation and addresses, addressed to tmac.
+func arpFrame(t *testing.T, op net_arp.Operation, smac net.HardwareAddr, sip net.IP, tmac net.HardwareAddr, tip net.IP) []byte {
+	t.Helper()
+
+	p, err := net_arp.NewPacket(op, smac, sip, tmac, tip)
+	if err != nil {
+		t.Fatalf("failed to create packet: %v", err)
+	}
+	pb, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal packet: %v", err)
+	}
+
+	return ethernetFrame(t, smac, tmac, pb)
+}
+
+// ethernetFrame returns an untagged ARP ethernet frame from src to dst
+// carrying payload.
+func ethernetFrame(t *testing.T, src, dst net.HardwareAddr, payload []byte) []byte {
+	t.Helper()
+
+	f := &ethernet.Frame{
+		Destination: dst,
+		Source:      src,
+		EtherType:   ethernet.EtherTypeARP,
+		Payload:     payload,
+	}
+	fb, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal frame: %v", err)
+	}
+	return fb
+}
+
+// nextWritten returns the ARP packet of the next frame written by the
+// Client using conn, failing the test if none is written within a second.
+func nextWritten(t *testing.T, conn *testConn) *net_arp.Packet {
+	t.Helper()
+
+	select {
+	case b := <-conn.out:
+		f := new(ethernet.Frame)
+		if err := f.UnmarshalBinary(b); err != nil {
+			t.Fatalf("failed to unmarshal written frame: %v", err)
+		}
+		p := new(net_arp.Packet)
+		if err := p.UnmarshalBinary(f.Payload); err != nil {
+			t.Fatalf("failed to unmarshal written packet: %v", err)
+		}
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a written frame")
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+package arp
+
+import (
+	"errors"
+	"time"
+)
+
+// errNilClock is returned by WithClock for a nil Clock.
+var errNilClock = errors.New("clock must not be nil")
+
+// A Clock provides the current time and timers to a Client. Replacing the
+// real clock with a fake one, using WithClock, makes timing dependent
+// behavior, such as reply delays, deterministic in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel which receives the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// WithClock sets the Clock used for the Client's timers, such as the reply
+// delay set with WithReplyDelay, for the timestamps of events and tapped
+// frames, and for the expiry of the Client's Cache. By default the system
+// clock is used.
+//
+// The Client also computes the read deadlines it sets from the Clock, such
+// as the default timeout and the duplicate grace window of Resolve, but
+// the net.PacketConn enforces them against its own notion of time: a real
+// socket always uses the system clock, while the fake connection of package
+// arptest uses its fake clock. Context deadlines are always relative to
+// the system clock.
+func WithClock(clk Clock) Option {
+	return func(cfg *config) error {
+		if clk == nil {
+			return errNilClock
+		}
+
+		cfg.clock = clk
+		return nil
+	}
+}
+
+// systemClock is a Clock using the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
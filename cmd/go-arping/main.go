@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	arp "github.com/pefish/go-arping"
-	"github.com/pefish/go-net-arp"
 	"log"
 	"net"
 	"time"
@@ -14,8 +13,9 @@ var (
 	ifaceFlag = flag.String("i", "eth0", "network interface to use for ARP request")
 
 	ipFlag = flag.String("ip", "", "IPv4 address destination for ARP request")
-)
 
+	dupFlag = flag.String("dup", "first", "behavior when several hosts reply: first, error or all")
+)
 
 func main() {
 	flag.Parse()
@@ -25,37 +25,43 @@ func main() {
 		log.Fatal(err)
 	}
 
-	c, err := arp.Dial(ifi)
+	var opts []arp.Option
+	switch *dupFlag {
+	case "first", "all":
+	case "error":
+		opts = append(opts, arp.WithDuplicatePolicy(arp.DuplicateError))
+	default:
+		log.Fatalf("unknown -dup value: %q", *dupFlag)
+	}
+
+	c, err := arp.Dial(ifi, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer c.Close()
 
-
 	if err := c.SetDeadline(time.Now().Add(time.Second)); err != nil {
 		log.Fatal(err)
 	}
 
 	ip := net.ParseIP(*ipFlag).To4()
 
-	err = c.Request(ip) // 发出arp请求
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var mac net.HardwareAddr
-	// 循环等待回复
-	for {
-		arp_, _, err := c.Read()
+	// 发出arp请求并等待回复
+	if *dupFlag == "all" {
+		macs, err := c.ResolveAll(ip)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		if arp_.Operation != net_arp.OperationReply || !arp_.SenderIP.Equal(ip) {
-			continue
+		for _, mac := range macs {
+			fmt.Printf("ip: %s -> mac地址: %s\n", ip, mac)
 		}
-		mac = arp_.SenderHardwareAddr
-		break
+		return
+	}
+
+	mac, err := c.Resolve(ip)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	fmt.Printf("ip: %s -> mac地址: %s\n", ip, mac)
@@ -0,0 +1,71 @@
+package arp
+
+import (
+	"net"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// zeroHardwareAddr is the target hardware address of an ARP request as
+// specified by RFC 826, which leaves it unset.
+var zeroHardwareAddr = net.HardwareAddr{0, 0, 0, 0, 0, 0}
+
+// ARPFields are the address fields of an ARP request sent by RequestCustom.
+// Fields other than TargetIP may be left nil to use their default value.
+type ARPFields struct {
+	// SenderHardwareAddr and SenderIP default to the addresses of the
+	// Client.
+	SenderHardwareAddr net.HardwareAddr
+	SenderIP           net.IP
+
+	// TargetHardwareAddr defaults to 00:00:00:00:00:00, as specified by
+	// RFC 826. Some devices set it to the broadcast address instead.
+	TargetHardwareAddr net.HardwareAddr
+
+	// TargetIP is the IPv4 address whose hardware address is requested.
+	TargetIP net.IP
+
+	// Destination is the destination address of the ethernet frame, and
+	// defaults to the broadcast address.
+	Destination net.HardwareAddr
+}
+
+// RequestCustom sends an ARP request with every address field set as
+// specified by fields, like Request otherwise. This allows crafting
+// requests which exactly match the format of a particular vendor, for
+// conformance or fuzz testing of devices with quirky parsers. Use
+// WriteFrame to control the raw frame instead.
+//
+// Hardware addresses must be 6 bytes long, and IP addresses must be IPv4
+// addresses. Errors are returned as an *OpError.
+func (c *Client) RequestCustom(fields ARPFields) error {
+	f := fields
+	if f.SenderHardwareAddr == nil {
+		f.SenderHardwareAddr = c.HardwareAddr()
+	}
+	if f.SenderIP == nil {
+		if c.ip == nil {
+			return c.opError("request", errNoIPv4Addr)
+		}
+		f.SenderIP = c.ip
+	}
+	if f.TargetHardwareAddr == nil {
+		f.TargetHardwareAddr = zeroHardwareAddr
+	}
+	if f.Destination == nil {
+		f.Destination = ethernet.BroadcastHardwareAddr
+	}
+
+	for _, mac := range []net.HardwareAddr{f.SenderHardwareAddr, f.TargetHardwareAddr, f.Destination} {
+		if len(mac) != ethernetAddrLen {
+			return c.opError("request", net_arp.ErrInvalidHardwareAddr)
+		}
+	}
+
+	p, err := net_arp.NewPacket(net_arp.OperationRequest, f.SenderHardwareAddr, f.SenderIP, f.TargetHardwareAddr, f.TargetIP)
+	if err != nil {
+		return c.opError("request", err)
+	}
+	return c.WriteTo(p, f.Destination)
+}
@@ -0,0 +1,78 @@
+package arp
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout is the default time Resolve waits for a reply when
+// neither a read deadline nor a context deadline is set.
+const DefaultTimeout = 2 * time.Second
+
+// bound sets the read deadline for an operation limited by ctx, and returns
+// the effective deadline together with a function which must be called to
+// restore the caller's read deadline once the operation completes.
+//
+// Every method which changes the socket's read deadline internally must
+// restore it, so a Read following, for instance, ResolveTimeout on a shared
+// Client is not cut short by the deadline of the earlier operation.
+//
+// The earliest of the context deadline and the read deadline set with
+// SetDeadline or SetReadDeadline applies. If neither is set, the default
+// timeout applies. The pending read is interrupted when ctx is canceled.
+func (c *Client) bound(ctx context.Context) (time.Time, func(), error) {
+	c.mu.Lock()
+	prior := c.readDeadline
+	c.mu.Unlock()
+
+	deadline := prior
+	if d, ok := ctx.Deadline(); ok {
+		if deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	} else if deadline.IsZero() && c.cfg.timeout > 0 {
+		deadline = c.cfg.clock.Now().Add(c.cfg.timeout)
+	}
+
+	if !deadline.Equal(prior) {
+		if err := c.p.SetReadDeadline(deadline); err != nil {
+			return time.Time{}, nil, c.opError("set deadline", err)
+		}
+	}
+
+	if ctx.Done() == nil {
+		return deadline, c.restoreReadDeadline, nil
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			_ = c.p.SetReadDeadline(c.cfg.clock.Now())
+		case <-stop:
+		}
+	}()
+
+	return deadline, func() {
+		close(stop)
+		<-done
+		c.restoreReadDeadline()
+	}, nil
+}
+
+// restoreReadDeadline sets the socket's read deadline back to the one most
+// recently set by the caller with SetDeadline or SetReadDeadline, which may
+// have changed while an operation shortened it. While the interface is
+// reported down, reads are kept interrupted instead.
+func (c *Client) restoreReadDeadline() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := c.readDeadline
+	if c.down {
+		deadline = c.cfg.clock.Now()
+	}
+	_ = c.p.SetReadDeadline(deadline)
+}
@@ -0,0 +1,69 @@
+package arp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+func TestBoundRestoresReadDeadline(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, c *Client, conn *testConn)
+	}{
+		{
+			name: "Resolve",
+			fn: func(t *testing.T, c *Client, conn *testConn) {
+				conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+
+				if _, err := c.Resolve(peerIP); err != nil {
+					t.Fatalf("failed to resolve: %v", err)
+				}
+			},
+		},
+		{
+			name: "ResolveTimeout",
+			fn: func(t *testing.T, c *Client, conn *testConn) {
+				// No reply, so the short timeout expires.
+				if _, err := c.ResolveTimeout(peerIP, 10*time.Millisecond); err == nil {
+					t.Fatal("expected a timeout")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, conn := testClient(t)
+			defer c.Close()
+
+			long := time.Now().Add(time.Hour)
+			if err := c.SetReadDeadline(long); err != nil {
+				t.Fatalf("failed to set read deadline: %v", err)
+			}
+
+			tt.fn(t, c, conn)
+
+			if got := conn.readDeadline(); !got.Equal(long) {
+				t.Fatalf("read deadline not restored:\n- want: %v\n-  got: %v", long, got)
+			}
+
+			// A following Read is not cut short by the earlier operation.
+			want := arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, testMAC, testIP)
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				conn.in <- want
+			}()
+
+			p, _, err := c.Read()
+			if err != nil {
+				t.Fatalf("failed to read after %s: %v", tt.name, err)
+			}
+			if p.Operation != net_arp.OperationRequest || !bytes.Equal(p.SenderIP, peerIP) {
+				t.Fatalf("unexpected packet: %+v", p)
+			}
+		})
+	}
+}
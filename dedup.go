@@ -0,0 +1,107 @@
+package arp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// maxDedupEntries bounds the number of events remembered by the
+// de-duplication of Monitor events.
+const maxDedupEntries = 4096
+
+// errInvalidDedupWindow is returned by WithDedup for a non-positive window.
+var errInvalidDedupWindow = errors.New("de-duplication window must be positive")
+
+// WithDedup makes Monitor suppress events which repeat an event delivered
+// less than window earlier: one with the same operation, the same sender
+// binding, the pair of sender IPv4 and hardware address, and the same
+// target IPv4 address. This keeps consumers focused on meaningful changes
+// on segments where chatty devices send gratuitous ARP frequently, while
+// the requests of a host for different targets, and the replies to them,
+// are all delivered. A binding which changes is delivered immediately, and
+// an event which keeps repeating is delivered again once per window, as a
+// refresh.
+//
+// De-duplication applies to Monitor, MonitorFor and MultiMonitor, each of
+// which de-duplicates its own stream, but not to Read or to passive helpers
+// which count packets, such as MeasureRate. At most 4096 events are
+// remembered, so memory stays bounded on large segments, at the cost of
+// repeats being delivered early when the limit is reached.
+func WithDedup(window time.Duration) Option {
+	return func(cfg *config) error {
+		if window <= 0 {
+			return errInvalidDedupWindow
+		}
+
+		cfg.dedup = window
+		return nil
+	}
+}
+
+// A dedup remembers when events were last delivered, to suppress repeats
+// within a window. It is not safe for concurrent use.
+type dedup struct {
+	window time.Duration
+	seen   map[dedupKey]time.Time
+}
+
+// A dedupKey identifies the events which repeat each other.
+type dedupKey struct {
+	op        net_arp.Operation
+	senderIP  string
+	senderMAC string
+	targetIP  string
+}
+
+// newDedup creates a dedup for window, or returns nil if window is zero.
+func newDedup(window time.Duration) *dedup {
+	if window <= 0 {
+		return nil
+	}
+
+	return &dedup{
+		window: window,
+		seen:   make(map[dedupKey]time.Time),
+	}
+}
+
+// suppress reports whether ev repeats an event delivered within the
+// window, and otherwise records it as delivered. A nil dedup suppresses
+// nothing.
+func (d *dedup) suppress(ev ARPEvent) bool {
+	if d == nil {
+		return false
+	}
+
+	k := dedupKey{
+		op:        ev.Packet.Operation,
+		senderIP:  ev.Packet.SenderIP.String(),
+		senderMAC: ev.Packet.SenderHardwareAddr.String(),
+		targetIP:  ev.Packet.TargetIP.String(),
+	}
+	if last, ok := d.seen[k]; ok && ev.Time.Sub(last) < d.window {
+		return true
+	}
+
+	if len(d.seen) >= maxDedupEntries {
+		d.evict(ev.Time)
+	}
+	d.seen[k] = ev.Time
+	return false
+}
+
+// evict forgets events whose window has elapsed at now, or every event if
+// none has.
+func (d *dedup) evict(now time.Time) {
+	for k, last := range d.seen {
+		if now.Sub(last) >= d.window {
+			delete(d.seen, k)
+		}
+	}
+
+	if len(d.seen) >= maxDedupEntries {
+		d.seen = make(map[dedupKey]time.Time)
+	}
+}
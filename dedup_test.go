@@ -0,0 +1,117 @@
+package arp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+func TestDedupSuppress(t *testing.T) {
+	const window = 10 * time.Second
+
+	var (
+		req = func(tip net.IP, offset time.Duration) ARPEvent {
+			return arpEvent(t, net_arp.OperationRequest, peerMAC, peerIP, tip, offset)
+		}
+		reply = func(offset time.Duration) ARPEvent {
+			return arpEvent(t, net_arp.OperationReply, peerMAC, peerIP, testIP, offset)
+		}
+	)
+
+	tests := []struct {
+		name string
+		ev   ARPEvent
+		// want reports whether ev is suppressed.
+		want bool
+	}{
+		{name: "first request", ev: req(testIP, 0)},
+		{name: "repeated request", ev: req(testIP, time.Second), want: true},
+		{name: "different target", ev: req(hostIP(10), 2*time.Second)},
+		{name: "different operation", ev: reply(3 * time.Second)},
+		{name: "repeated reply", ev: reply(4 * time.Second), want: true},
+		{
+			name: "changed binding",
+			ev:   arpEvent(t, net_arp.OperationRequest, testMAC, peerIP, testIP, 5*time.Second),
+		},
+		{name: "just within the window", ev: req(testIP, window-time.Nanosecond), want: true},
+		{name: "refresh after the window", ev: req(testIP, window)},
+		{name: "repeated refresh", ev: req(testIP, window+time.Second), want: true},
+	}
+
+	// The cases run in order against a single dedup.
+	d := newDedup(window)
+	for _, tt := range tests {
+		if got := d.suppress(tt.ev); got != tt.want {
+			t.Fatalf("%s: unexpected suppression: want %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if newDedup(0).suppress(req(testIP, 0)) {
+		t.Fatal("nil dedup suppressed an event")
+	}
+}
+
+func TestDedupEviction(t *testing.T) {
+	const window = 10 * time.Second
+
+	// ev returns a distinct request for each n, read at offset.
+	ev := func(n int, offset time.Duration) ARPEvent {
+		tip := net.IPv4(198, 51, byte(n>>8), byte(n)).To4()
+		return arpEvent(t, net_arp.OperationRequest, peerMAC, peerIP, tip, offset)
+	}
+
+	t.Run("expired events", func(t *testing.T) {
+		d := newDedup(window)
+
+		// Half of the entries are recorded at the start, and half later.
+		half := maxDedupEntries / 2
+		for i := 0; i < maxDedupEntries; i++ {
+			offset := time.Duration(0)
+			if i >= half {
+				offset = window / 2
+			}
+			if d.suppress(ev(i, offset)) {
+				t.Fatalf("event %d suppressed", i)
+			}
+		}
+		if len(d.seen) != maxDedupEntries {
+			t.Fatalf("unexpected number of entries: %d", len(d.seen))
+		}
+
+		// Once the first half expires, a new event evicts it, and the
+		// second half remains suppressed.
+		if d.suppress(ev(maxDedupEntries, window)) {
+			t.Fatal("new event suppressed")
+		}
+		if want := maxDedupEntries - half + 1; len(d.seen) != want {
+			t.Fatalf("unexpected number of entries after eviction: want %d, got %d", want, len(d.seen))
+		}
+		if !d.suppress(ev(half, window)) {
+			t.Fatal("unexpired event was evicted")
+		}
+	})
+
+	t.Run("full window", func(t *testing.T) {
+		d := newDedup(window)
+
+		for i := 0; i < maxDedupEntries; i++ {
+			if d.suppress(ev(i, time.Second)) {
+				t.Fatalf("event %d suppressed", i)
+			}
+		}
+
+		// No entry has expired, so every entry is forgotten, and repeats
+		// are delivered early.
+		if d.suppress(ev(maxDedupEntries, 2*time.Second)) {
+			t.Fatal("new event suppressed")
+		}
+		if len(d.seen) != 1 {
+			t.Fatalf("unexpected number of entries after reset: %d", len(d.seen))
+		}
+		if d.suppress(ev(0, 3*time.Second)) {
+			t.Fatal("forgotten event suppressed")
+		}
+	})
+}
@@ -0,0 +1,429 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// defaultDetectWindow replaces a non-positive window passed to a detector.
+const defaultDetectWindow = time.Minute
+
+// detectParams returns window and threshold as used by the detectors: a
+// non-positive window is replaced with one minute, and a threshold below 1
+// with 1, so a detector always tracks a window and never reports every
+// packet.
+func detectParams(window time.Duration, threshold int) (time.Duration, int) {
+	if window <= 0 {
+		window = defaultDetectWindow
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return window, threshold
+}
+
+// A ScanEvent reports a host which requested many distinct IPv4 addresses
+// within a short time, and is likely scanning the segment.
+type ScanEvent struct {
+	// HardwareAddr is the sender hardware address of the requests.
+	HardwareAddr net.HardwareAddr
+
+	// Targets are the distinct addresses requested within the window, in
+	// ascending order, of which Unanswered received no reply.
+	Targets    []net.IP
+	Unanswered int
+
+	// Time is the time of the request which triggered the event.
+	Time time.Time
+}
+
+// DetectScanning passively tracks, for each sender hardware address, the
+// distinct IPv4 addresses it requested within the last window, and emits a
+// ScanEvent when their weighted count exceeds threshold. Requests for hosts
+// which do not exist are the hallmark of a scan, so a target which nobody
+// answered within window counts fully, while an answered target counts
+// half. Once an event is emitted for a host, its tracking starts over, so
+// a scanner which keeps going is reported again after another threshold
+// of targets.
+//
+// A non-positive window is replaced with one minute, and a threshold below
+// 1 with 1. Gratuitous announcements are ignored. DetectScanning is built
+// on Monitor, and the returned channel is closed under the same
+// conditions. Call the returned function to stop detection.
+func (c *Client) DetectScanning(window time.Duration, threshold int) (<-chan ScanEvent, func()) {
+	events := make(chan ScanEvent, monitorBuffer)
+	d := newScanDetector(window, threshold)
+	return events, c.monitorChan(func(ev ARPEvent, quit <-chan struct{}) {
+		sev, ok := d.observe(ev)
+		if !ok {
+			return
+		}
+
+		select {
+		case events <- sev:
+		case <-quit:
+		}
+	}, func() { close(events) })
+}
+
+// A scanDetector holds the state of DetectScanning. It is not safe for
+// concurrent use.
+type scanDetector struct {
+	window    time.Duration
+	threshold int
+
+	// sources maps a sender hardware address to the time it last
+	// requested each target, and answers maps an IPv4 address to the
+	// time it last replied.
+	sources map[string]map[string]time.Time
+	answers map[string]time.Time
+
+	// swept is when stale state was last removed.
+	swept time.Time
+}
+
+// newScanDetector creates a scanDetector, adjusting window and threshold
+// with detectParams.
+func newScanDetector(window time.Duration, threshold int) *scanDetector {
+	window, threshold = detectParams(window, threshold)
+	return &scanDetector{
+		window:    window,
+		threshold: threshold,
+		sources:   make(map[string]map[string]time.Time),
+		answers:   make(map[string]time.Time),
+	}
+}
+
+// observe records ev, and returns a ScanEvent if its sender crossed the
+// threshold.
+func (d *scanDetector) observe(ev ARPEvent) (ScanEvent, bool) {
+	now := ev.Time
+	d.sweep(now)
+
+	p := ev.Packet
+	switch p.Operation {
+	case net_arp.OperationReply:
+		d.answers[p.SenderIP.String()] = now
+		return ScanEvent{}, false
+	case net_arp.OperationRequest:
+	default:
+		return ScanEvent{}, false
+	}
+	if IPsEqual(p.SenderIP, p.TargetIP) {
+		return ScanEvent{}, false
+	}
+
+	src := p.SenderHardwareAddr.String()
+	targets, ok := d.sources[src]
+	if !ok {
+		targets = make(map[string]time.Time)
+		d.sources[src] = targets
+	}
+	targets[p.TargetIP.String()] = now
+
+	// Unanswered targets count twice as much as answered ones.
+	var score, unanswered int
+	ips := make([]net.IP, 0, len(targets))
+	for k, t := range targets {
+		if now.Sub(t) >= d.window {
+			delete(targets, k)
+			continue
+		}
+
+		if at, ok := d.answers[k]; ok && now.Sub(at) < d.window {
+			score++
+		} else {
+			score += 2
+			unanswered++
+		}
+		ips = append(ips, net.ParseIP(k).To4())
+	}
+
+	if score <= 2*d.threshold {
+		return ScanEvent{}, false
+	}
+
+	delete(d.sources, src)
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(ips[i], ips[j]) < 0
+	})
+
+	return ScanEvent{
+		HardwareAddr: p.SenderHardwareAddr,
+		Targets:      ips,
+		Unanswered:   unanswered,
+		Time:         now,
+	}, true
+}
+
+// sweep removes state older than the window, at most once per window, so
+// memory stays bounded by the traffic of a single window.
+func (d *scanDetector) sweep(now time.Time) {
+	if now.Sub(d.swept) < d.window {
+		return
+	}
+	d.swept = now
+
+	for src, targets := range d.sources {
+		for k, t := range targets {
+			if now.Sub(t) >= d.window {
+				delete(targets, k)
+			}
+		}
+		if len(targets) == 0 {
+			delete(d.sources, src)
+		}
+	}
+
+	for k, t := range d.answers {
+		if now.Sub(t) >= d.window {
+			delete(d.answers, k)
+		}
+	}
+}
+
+// DetectUnsolicitedReplies passively correlates ARP replies with the
+// requests observed on the segment, and emits an event for each reply
+// which does not answer a request seen within the last window. A reply
+// answers a request if the request asked for the sender IPv4 address of
+// the reply, and came from its target IPv4 address. Unsolicited replies
+// are a classic indicator of ARP spoofing, although gratuitous replies,
+// which are also reported, are sometimes sent legitimately, for instance
+// on failover.
+//
+// A non-positive window is replaced with one minute. Requests are only
+// observed if the Client reads them. Requests sent by
+// the Client itself are usually not read back from the socket, so replies
+// to them are reported as unsolicited. DetectUnsolicitedReplies is built
+// on Monitor, and the returned channel is closed under the same conditions.
+// Call the returned function to stop detection.
+func (c *Client) DetectUnsolicitedReplies(window time.Duration) (<-chan ARPEvent, func()) {
+	events := make(chan ARPEvent, monitorBuffer)
+	d := newReplyDetector(window)
+	return events, c.monitorChan(func(ev ARPEvent, quit <-chan struct{}) {
+		if !d.unsolicited(ev) {
+			return
+		}
+
+		select {
+		case events <- ev:
+		case <-quit:
+		}
+	}, func() { close(events) })
+}
+
+// A replyDetector holds the state of DetectUnsolicitedReplies. It is not
+// safe for concurrent use.
+type replyDetector struct {
+	window time.Duration
+
+	// requests maps a target and requester address pair to the time the
+	// last request for the target was seen from the requester.
+	requests map[string]time.Time
+
+	// swept is when stale requests were last removed.
+	swept time.Time
+}
+
+// newReplyDetector creates a replyDetector, adjusting window with
+// detectParams.
+func newReplyDetector(window time.Duration) *replyDetector {
+	window, _ = detectParams(window, 0)
+	return &replyDetector{
+		window:   window,
+		requests: make(map[string]time.Time),
+	}
+}
+
+// unsolicited records ev if it is a request, and reports whether it is a
+// reply to no recent request.
+func (d *replyDetector) unsolicited(ev ARPEvent) bool {
+	now := ev.Time
+	d.sweep(now)
+
+	p := ev.Packet
+	switch p.Operation {
+	case net_arp.OperationRequest:
+		d.requests[p.TargetIP.String()+"/"+p.SenderIP.String()] = now
+		return false
+	case net_arp.OperationReply:
+	default:
+		return false
+	}
+
+	at, ok := d.requests[p.SenderIP.String()+"/"+p.TargetIP.String()]
+	return !ok || now.Sub(at) >= d.window
+}
+
+// sweep removes requests older than the window, at most once per window.
+func (d *replyDetector) sweep(now time.Time) {
+	if now.Sub(d.swept) < d.window {
+		return
+	}
+	d.swept = now
+
+	for k, t := range d.requests {
+		if now.Sub(t) >= d.window {
+			delete(d.requests, k)
+		}
+	}
+}
+
+// A ClaimEvent reports a hardware address which claimed an implausibly
+// large number of IPv4 addresses within a short time, which is a strong
+// indicator of a host poisoning the ARP caches of its neighbors.
+type ClaimEvent struct {
+	// HardwareAddr is the sender hardware address of the claims.
+	HardwareAddr net.HardwareAddr
+
+	// IPs are the distinct addresses claimed within the window, in
+	// ascending order.
+	IPs []net.IP
+
+	// Time is the time of the claim which triggered the event.
+	Time time.Time
+}
+
+// DetectMACClaimCount passively tracks, for each sender hardware address,
+// the distinct IPv4 addresses it claimed within the last window, in
+// replies and gratuitous announcements, and emits a ClaimEvent when there
+// are more than threshold of them. Like DetectScanning, the tracking of a
+// hardware address starts over once an event is emitted for it, and a
+// non-positive window is replaced with one minute, and a threshold below 1
+// with 1.
+//
+// Proxy ARP responders and routers legitimately answer for many addresses;
+// exclude them with WithTrustedClaimers. DetectMACClaimCount is built on
+// Monitor, and the returned channel is closed under the same conditions.
+// Call the returned function to stop detection.
+func (c *Client) DetectMACClaimCount(window time.Duration, threshold int) (<-chan ClaimEvent, func()) {
+	events := make(chan ClaimEvent, monitorBuffer)
+	d := newClaimDetector(window, threshold, c.cfg.trustedClaimers)
+	return events, c.monitorChan(func(ev ARPEvent, quit <-chan struct{}) {
+		cev, ok := d.observe(ev)
+		if !ok {
+			return
+		}
+
+		select {
+		case events <- cev:
+		case <-quit:
+		}
+	}, func() { close(events) })
+}
+
+// WithTrustedClaimers excludes the hardware addresses macs, such as those
+// of proxy ARP responders, from DetectMACClaimCount.
+func WithTrustedClaimers(macs ...net.HardwareAddr) Option {
+	return func(cfg *config) error {
+		if cfg.trustedClaimers == nil {
+			cfg.trustedClaimers = make(map[string]struct{}, len(macs))
+		}
+		for _, mac := range macs {
+			cfg.trustedClaimers[mac.String()] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// A claimDetector holds the state of DetectMACClaimCount. It is not safe
+// for concurrent use.
+type claimDetector struct {
+	window    time.Duration
+	threshold int
+	trusted   map[string]struct{}
+
+	// sources maps a sender hardware address to the time it last claimed
+	// each IPv4 address.
+	sources map[string]map[string]time.Time
+
+	// swept is when stale state was last removed.
+	swept time.Time
+}
+
+// newClaimDetector creates a claimDetector which ignores the hardware
+// addresses in trusted, adjusting window and threshold with detectParams.
+func newClaimDetector(window time.Duration, threshold int, trusted map[string]struct{}) *claimDetector {
+	window, threshold = detectParams(window, threshold)
+	return &claimDetector{
+		window:    window,
+		threshold: threshold,
+		trusted:   trusted,
+		sources:   make(map[string]map[string]time.Time),
+	}
+}
+
+// observe records ev, and returns a ClaimEvent if its sender crossed the
+// threshold.
+func (d *claimDetector) observe(ev ARPEvent) (ClaimEvent, bool) {
+	now := ev.Time
+	d.sweep(now)
+
+	p := ev.Packet
+	gratuitous := p.Operation == net_arp.OperationRequest && IPsEqual(p.SenderIP, p.TargetIP)
+	if p.Operation != net_arp.OperationReply && !gratuitous {
+		return ClaimEvent{}, false
+	}
+	if p.SenderIP.Equal(net.IPv4zero) {
+		return ClaimEvent{}, false
+	}
+
+	src := p.SenderHardwareAddr.String()
+	if _, ok := d.trusted[src]; ok {
+		return ClaimEvent{}, false
+	}
+
+	claims, ok := d.sources[src]
+	if !ok {
+		claims = make(map[string]time.Time)
+		d.sources[src] = claims
+	}
+	claims[p.SenderIP.String()] = now
+
+	ips := make([]net.IP, 0, len(claims))
+	for k, t := range claims {
+		if now.Sub(t) >= d.window {
+			delete(claims, k)
+			continue
+		}
+		ips = append(ips, net.ParseIP(k).To4())
+	}
+
+	if len(ips) <= d.threshold {
+		return ClaimEvent{}, false
+	}
+
+	delete(d.sources, src)
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(ips[i], ips[j]) < 0
+	})
+
+	return ClaimEvent{
+		HardwareAddr: p.SenderHardwareAddr,
+		IPs:          ips,
+		Time:         now,
+	}, true
+}
+
+// sweep removes claims older than the window, at most once per window.
+func (d *claimDetector) sweep(now time.Time) {
+	if now.Sub(d.swept) < d.window {
+		return
+	}
+	d.swept = now
+
+	for src, claims := range d.sources {
+		for k, t := range claims {
+			if now.Sub(t) >= d.window {
+				delete(claims, k)
+			}
+		}
+		if len(claims) == 0 {
+			delete(d.sources, src)
+		}
+	}
+}
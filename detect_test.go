@@ -0,0 +1,220 @@
+package arp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// detectStart is the time of the first synthetic event in detector tests.
+var detectStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// arpEvent returns an ARPEvent for a packet with the specified operation
+// and addresses, read at detectStart plus offset.
+func arpEvent(t *testing.T, op net_arp.Operation, smac net.HardwareAddr, sip, tip net.IP, offset time.Duration) ARPEvent {
+	t.Helper()
+
+	p, err := net_arp.NewPacket(op, smac, sip, make(net.HardwareAddr, 6), tip)
+	if err != nil {
+		t.Fatalf("failed to create packet: %v", err)
+	}
+
+	return ARPEvent{
+		Packet: p,
+		Frame:  &ethernet.Frame{Source: smac, Destination: ethernet.BroadcastHardwareAddr},
+		Time:   detectStart.Add(offset),
+	}
+}
+
+func TestScanDetector(t *testing.T) {
+	const window = time.Minute
+	scanner := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x0f}
+
+	t.Run("unanswered targets", func(t *testing.T) {
+		d := newScanDetector(window, 2)
+
+		for i := byte(10); i < 12; i++ {
+			if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(i), time.Duration(i)*time.Second)); ok {
+				t.Fatalf("event after %d targets", i-9)
+			}
+		}
+
+		ev, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(12), 12*time.Second))
+		if !ok {
+			t.Fatal("no event after 3 unanswered targets")
+		}
+		if ev.HardwareAddr.String() != scanner.String() || len(ev.Targets) != 3 || ev.Unanswered != 3 ||
+			!ev.Targets[0].Equal(hostIP(10)) || !ev.Time.Equal(detectStart.Add(12*time.Second)) {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+
+		// Tracking starts over after an event.
+		if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(13), 13*time.Second)); ok {
+			t.Fatal("event immediately after the previous one")
+		}
+	})
+
+	t.Run("answered targets count half", func(t *testing.T) {
+		d := newScanDetector(window, 2)
+
+		for i := byte(10); i < 14; i++ {
+			d.observe(arpEvent(t, net_arp.OperationReply, peerMAC, hostIP(i), peerIP, 0))
+		}
+		for i := byte(10); i < 14; i++ {
+			if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(i), time.Second)); ok {
+				t.Fatalf("event after %d answered targets", i-9)
+			}
+		}
+
+		ev, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(14), time.Second))
+		if !ok {
+			t.Fatal("no event after 5 answered targets")
+		}
+		if len(ev.Targets) != 5 || ev.Unanswered != 1 {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	})
+
+	t.Run("targets expire after the window", func(t *testing.T) {
+		d := newScanDetector(window, 2)
+
+		for i, offset := range []time.Duration{0, time.Second, window, window + time.Second} {
+			if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(byte(10+i)), offset)); ok {
+				t.Fatalf("event for request %d after the window passed", i)
+			}
+		}
+	})
+
+	t.Run("gratuitous requests ignored", func(t *testing.T) {
+		d := newScanDetector(window, 0)
+
+		if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, peerIP, 0)); ok {
+			t.Fatal("event for a gratuitous request")
+		}
+	})
+}
+
+func TestReplyDetector(t *testing.T) {
+	const window = time.Minute
+	d := newReplyDetector(window)
+
+	// testIP asks for peerIP, which answers within the window.
+	if d.unsolicited(arpEvent(t, net_arp.OperationRequest, testMAC, testIP, peerIP, 0)) {
+		t.Fatal("request reported as an unsolicited reply")
+	}
+	if d.unsolicited(arpEvent(t, net_arp.OperationReply, peerMAC, peerIP, testIP, time.Second)) {
+		t.Fatal("solicited reply reported")
+	}
+
+	// A reply to another requester, or for another address, was not asked
+	// for.
+	if !d.unsolicited(arpEvent(t, net_arp.OperationReply, peerMAC, peerIP, hostIP(10), time.Second)) {
+		t.Fatal("reply to another requester not reported")
+	}
+	if !d.unsolicited(arpEvent(t, net_arp.OperationReply, peerMAC, hostIP(10), testIP, time.Second)) {
+		t.Fatal("reply for another address not reported")
+	}
+
+	// Once the window passed, the request no longer solicits a reply.
+	if !d.unsolicited(arpEvent(t, net_arp.OperationReply, peerMAC, peerIP, testIP, window)) {
+		t.Fatal("reply after the window not reported")
+	}
+}
+
+func TestClaimDetector(t *testing.T) {
+	const window = time.Minute
+	poisoner := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x0e}
+	proxy := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x0d}
+
+	claim := func(t *testing.T, d *claimDetector, mac net.HardwareAddr, ip net.IP, offset time.Duration) (ClaimEvent, bool) {
+		return d.observe(arpEvent(t, net_arp.OperationReply, mac, ip, testIP, offset))
+	}
+
+	t.Run("replies and announcements", func(t *testing.T) {
+		d := newClaimDetector(window, 2, nil)
+
+		if _, ok := claim(t, d, poisoner, hostIP(10), 0); ok {
+			t.Fatal("event after 1 claim")
+		}
+		// A gratuitous announcement counts as a claim.
+		if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, poisoner, hostIP(11), hostIP(11), time.Second)); ok {
+			t.Fatal("event after 2 claims")
+		}
+		// A repeated claim does not count twice.
+		if _, ok := claim(t, d, poisoner, hostIP(11), 2*time.Second); ok {
+			t.Fatal("event after a repeated claim")
+		}
+
+		ev, ok := claim(t, d, poisoner, hostIP(12), 3*time.Second)
+		if !ok {
+			t.Fatal("no event after 3 claims")
+		}
+		if ev.HardwareAddr.String() != poisoner.String() || len(ev.IPs) != 3 || !ev.IPs[2].Equal(hostIP(12)) {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	})
+
+	t.Run("ignored packets", func(t *testing.T) {
+		d := newClaimDetector(window, 0, map[string]struct{}{proxy.String(): {}})
+
+		if _, ok := claim(t, d, proxy, hostIP(10), 0); ok {
+			t.Fatal("event for a trusted claimer")
+		}
+		if _, ok := claim(t, d, poisoner, net.IPv4zero.To4(), 0); ok {
+			t.Fatal("event for a claim of the unspecified address")
+		}
+		if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, poisoner, hostIP(10), testIP, 0)); ok {
+			t.Fatal("event for an ordinary request")
+		}
+	})
+
+	t.Run("claims expire after the window", func(t *testing.T) {
+		d := newClaimDetector(window, 2, nil)
+
+		for i, offset := range []time.Duration{0, time.Second, window, window + time.Second} {
+			if _, ok := claim(t, d, poisoner, hostIP(byte(10+i)), offset); ok {
+				t.Fatalf("event for claim %d after the window passed", i)
+			}
+		}
+	})
+}
+
+func TestDetectorParams(t *testing.T) {
+	scanner := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x0f}
+
+	for _, window := range []time.Duration{0, -time.Second} {
+		// A non-positive window would expire every target at once, so a
+		// scan would never be reported.
+		d := newScanDetector(window, 1)
+		var reported bool
+		for i := byte(10); i < 12; i++ {
+			_, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(i), time.Duration(i)*time.Second))
+			reported = reported || ok
+		}
+		if !reported {
+			t.Fatalf("scan not reported with window %v", window)
+		}
+
+		r := newReplyDetector(window)
+		r.unsolicited(arpEvent(t, net_arp.OperationRequest, testMAC, testIP, peerIP, 0))
+		if r.unsolicited(arpEvent(t, net_arp.OperationReply, peerMAC, peerIP, testIP, time.Second)) {
+			t.Fatalf("solicited reply reported with window %v", window)
+		}
+	}
+
+	for _, threshold := range []int{0, -1} {
+		// A threshold below 1 would report every request and claim.
+		d := newScanDetector(time.Minute, threshold)
+		if _, ok := d.observe(arpEvent(t, net_arp.OperationRequest, scanner, peerIP, hostIP(10), 0)); ok {
+			t.Fatalf("first request reported with threshold %d", threshold)
+		}
+
+		c := newClaimDetector(time.Minute, threshold, nil)
+		if _, ok := c.observe(arpEvent(t, net_arp.OperationReply, scanner, hostIP(10), testIP, 0)); ok {
+			t.Fatalf("first claim reported with threshold %d", threshold)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package arp
+
+import (
+	"net"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// EncodeRequest returns the marshaled ethernet frame of an ARP request from
+// src and srcIP asking for the hardware address of dstIP, without using a
+// socket, for callers which carry ARP over their own transport. The frame
+// is identical to the one sent by Client.Request with the default options.
+//
+// The frame is laid out as follows, with multi-byte fields in network byte
+// order, and is 60 bytes long:
+//
+//	 0  6 bytes  destination: ff:ff:ff:ff:ff:ff
+//	 6  6 bytes  source: src
+//	12  2 bytes  EtherType: 0x0806 (ARP)
+//	14  2 bytes  hardware type: 1 (Ethernet)
+//	16  2 bytes  protocol type: 0x0800 (IPv4)
+//	18  1 byte   hardware address length: 6
+//	19  1 byte   protocol address length: 4
+//	20  2 bytes  operation: 1 (request)
+//	22  6 bytes  sender hardware address: src
+//	28  4 bytes  sender IP address: srcIP
+//	32  6 bytes  target hardware address: ff:ff:ff:ff:ff:ff
+//	38  4 bytes  target IP address: dstIP
+//	42 18 bytes  zero padding to the minimum frame length
+//
+// src must be a 6 byte hardware address, and srcIP and dstIP must be IPv4
+// addresses.
+func EncodeRequest(src net.HardwareAddr, srcIP, dstIP net.IP) ([]byte, error) {
+	return encodeFrame(net_arp.OperationRequest, src, srcIP, ethernet.BroadcastHardwareAddr, dstIP)
+}
+
+// EncodeReply returns the marshaled ethernet frame of an ARP reply from src
+// and srcIP to dst and dstIP, announcing that srcIP is at src, without
+// using a socket. The frame is addressed to dst, and is laid out like the
+// frame of EncodeRequest, with operation 2 (reply) and dst as the
+// destination and target hardware address.
+//
+// src and dst must be 6 byte hardware addresses, and srcIP and dstIP must
+// be IPv4 addresses.
+func EncodeReply(src net.HardwareAddr, srcIP net.IP, dst net.HardwareAddr, dstIP net.IP) ([]byte, error) {
+	return encodeFrame(net_arp.OperationReply, src, srcIP, dst, dstIP)
+}
+
+// encodeFrame marshals an ARP packet with the specified fields into an
+// ethernet frame addressed to dst.
+func encodeFrame(op net_arp.Operation, src net.HardwareAddr, srcIP net.IP, dst net.HardwareAddr, dstIP net.IP) ([]byte, error) {
+	if len(src) != ethernetAddrLen || len(dst) != ethernetAddrLen {
+		return nil, net_arp.ErrInvalidHardwareAddr
+	}
+
+	p, err := net_arp.NewPacket(op, src, srcIP, dst, dstIP)
+	if err != nil {
+		return nil, err
+	}
+
+	pb, err := p.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &ethernet.Frame{
+		Destination: dst,
+		Source:      src,
+		EtherType:   ethernet.EtherTypeARP,
+		Payload:     pb,
+	}
+	return f.MarshalBinary()
+}
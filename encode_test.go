@@ -0,0 +1,157 @@
+package arp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+)
+
+// goldenFrame decodes a hex dump with whitespace into frame bytes.
+func goldenFrame(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatalf("failed to decode golden frame: %v", err)
+	}
+	return b
+}
+
+func TestEncodeGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		encode func() ([]byte, error)
+		want   string
+	}{
+		{
+			name: "request",
+			encode: func() ([]byte, error) {
+				return EncodeRequest(testMAC, testIP, peerIP)
+			},
+			want: `
+				ffffffffffff 020000000001 0806
+				0001 0800 06 04 0001
+				020000000001 c0000201
+				ffffffffffff c0000202
+				000000000000000000000000000000000000
+			`,
+		},
+		{
+			name: "reply",
+			encode: func() ([]byte, error) {
+				return EncodeReply(testMAC, testIP, peerMAC, peerIP)
+			},
+			want: `
+				020000000002 020000000001 0806
+				0001 0800 06 04 0002
+				020000000001 c0000201
+				020000000002 c0000202
+				000000000000000000000000000000000000
+			`,
+		},
+		{
+			name: "16 byte IPv4 addresses",
+			encode: func() ([]byte, error) {
+				return EncodeRequest(testMAC, testIP.To16(), peerIP.To16())
+			},
+			want: `
+				ffffffffffff 020000000001 0806
+				0001 0800 06 04 0001
+				020000000001 c0000201
+				ffffffffffff c0000202
+				000000000000000000000000000000000000
+			`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.encode()
+			if err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+
+			want := goldenFrame(t, tt.want)
+			if len(want) != 60 {
+				t.Fatalf("golden frame is %d bytes, want 60", len(want))
+			}
+			if !bytes.Equal(want, got) {
+				t.Fatalf("unexpected frame:\n- want: %x\n-  got: %x", want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	short := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x01}
+	ipv6 := net.ParseIP("2001:db8::1")
+
+	tests := []struct {
+		name   string
+		encode func() ([]byte, error)
+	}{
+		{
+			name: "request short source MAC",
+			encode: func() ([]byte, error) {
+				return EncodeRequest(short, testIP, peerIP)
+			},
+		},
+		{
+			name: "request IPv6 source",
+			encode: func() ([]byte, error) {
+				return EncodeRequest(testMAC, ipv6, peerIP)
+			},
+		},
+		{
+			name: "request IPv6 target",
+			encode: func() ([]byte, error) {
+				return EncodeRequest(testMAC, testIP, ipv6)
+			},
+		},
+		{
+			name: "reply short source MAC",
+			encode: func() ([]byte, error) {
+				return EncodeReply(short, testIP, peerMAC, peerIP)
+			},
+		},
+		{
+			name: "reply short destination MAC",
+			encode: func() ([]byte, error) {
+				return EncodeReply(testMAC, testIP, short, peerIP)
+			},
+		},
+		{
+			name: "reply IPv6 target",
+			encode: func() ([]byte, error) {
+				return EncodeReply(testMAC, testIP, peerMAC, ipv6)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if b, err := tt.encode(); err == nil {
+				t.Fatalf("expected an error, got frame %x", b)
+			}
+		})
+	}
+}
+
+func TestEncodeRequestMatchesRequest(t *testing.T) {
+	c, conn := testClient(t)
+	defer c.Close()
+
+	if err := c.Request(peerIP); err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+
+	want, err := EncodeRequest(testMAC, testIP, peerIP)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if got := <-conn.out; !bytes.Equal(want, got) {
+		t.Fatalf("frames differ:\n- encoded: %x\n-    sent: %x", want, got)
+	}
+}
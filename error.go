@@ -0,0 +1,79 @@
+package arp
+
+import (
+	"errors"
+	"net"
+)
+
+var _ net.Error = &OpError{}
+
+// An OpError is returned by a Client's operations, such as Read, WriteTo
+// and Request. It records the operation and the network interface on which
+// it failed, so errors from Clients on different interfaces can be told
+// apart.
+type OpError struct {
+	// Op is the operation which caused the error, such as "read" or
+	// "write".
+	Op string
+
+	// Interface is the name of the Client's network interface.
+	Interface string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// opError wraps err in an *OpError for op on the Client's interface.
+func (c *Client) opError(op string, err error) error {
+	return &OpError{
+		Op:        op,
+		Interface: c.name,
+		Err:       err,
+	}
+}
+
+// Error implements error.
+func (e *OpError) Error() string {
+	return "arp " + e.Op + " " + e.Interface + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the underlying error is a timeout.
+func (e *OpError) Timeout() bool {
+	return isTimeout(e.Err)
+}
+
+// A frameError is the underlying error of an *OpError returned by Read for
+// a frame which could not be decoded, as opposed to a failure of the
+// socket. Read loops such as Monitor and Serve skip such frames, since any
+// host on the segment can send them.
+type frameError struct {
+	err error
+}
+
+// Error implements error.
+func (e *frameError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the decoding error.
+func (e *frameError) Unwrap() error {
+	return e.err
+}
+
+// isFrameError reports whether err was caused by a frame which could not
+// be decoded, including one rejected by WithStrictFrames.
+func isFrameError(err error) bool {
+	var fe *frameError
+	return errors.As(err, &fe)
+}
+
+// Temporary reports whether the underlying error is temporary.
+func (e *OpError) Temporary() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Temporary()
+}
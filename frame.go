@@ -0,0 +1,78 @@
+package arp
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/pefish/go-ethernet"
+)
+
+const (
+	// headerLen is the length of an untagged ethernet header.
+	headerLen = 14
+
+	// fcsLen is the length of an ethernet frame check sequence.
+	fcsLen = 4
+)
+
+// UnmarshalAuto unmarshals b into an ethernet frame, detecting whether b
+// ends with a frame check sequence. It reports whether a valid FCS was
+// present, in which case it is stripped before decoding, as by
+// ethernet.Frame.UnmarshalFCS. Otherwise b is decoded as by
+// ethernet.Frame.UnmarshalBinary. This is useful when ingesting captures
+// from sources which may or may not include the FCS.
+//
+// The heuristic treats the trailing 4 bytes as an FCS only if they are the
+// CRC-32 of the preceding bytes, so it has limitations:
+//   - A frame without FCS whose last 4 bytes happen to equal the CRC-32 of
+//     the bytes before them is misdetected as having one. This is unlikely
+//     for ordinary traffic (about 1 in 2^32), but a payload can be crafted
+//     to trigger it deliberately.
+//   - A frame with a corrupted FCS cannot be told apart from one without
+//     FCS, so it is reported as having none, and the 4 checksum bytes
+//     remain at the end of the payload.
+//   - An FCS is never detected on frames shorter than 18 bytes.
+func UnmarshalAuto(b []byte) (*ethernet.Frame, bool, error) {
+	f := new(ethernet.Frame)
+
+	if len(b) >= headerLen+fcsLen {
+		n := len(b) - fcsLen
+		if binary.BigEndian.Uint32(b[n:]) == crc32.ChecksumIEEE(b[:n]) {
+			if err := f.UnmarshalBinary(b[:n]); err != nil {
+				return nil, false, err
+			}
+			return f, true, nil
+		}
+	}
+
+	if err := f.UnmarshalBinary(b); err != nil {
+		return nil, false, err
+	}
+	return f, false, nil
+}
+
+// IsEthernetII reports whether f is an Ethernet II frame, whose EtherType
+// field holds a protocol type. Otherwise the field is the payload length
+// of an IEEE 802.3 frame, and IEEE8023Payload may be used to interpret it.
+// The field is a type if its value is at least 0x0600 (1536), as in
+// ethernet.Frame.UnmarshalBinary, which stores the field in EtherType in
+// both cases.
+func IsEthernetII(f *ethernet.Frame) bool {
+	return f.EtherType >= minEtherType
+}
+
+// IEEE8023Payload returns the payload of the IEEE 802.3 frame f, without the
+// padding which follows it, using the length stored in its EtherType
+// field. It reports false if f is an Ethernet II frame, or if the length
+// exceeds the payload.
+func IEEE8023Payload(f *ethernet.Frame) ([]byte, bool) {
+	if IsEthernetII(f) {
+		return nil, false
+	}
+
+	n := int(f.EtherType)
+	if n > len(f.Payload) {
+		return nil, false
+	}
+	return f.Payload[:n], true
+}
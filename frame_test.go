@@ -0,0 +1,198 @@
+package arp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/pefish/go-ethernet"
+)
+
+// rawFrame returns an untagged ethernet frame from peerMAC to testMAC whose
+// EtherType or length field is typ, optionally followed by its FCS.
+func rawFrame(typ uint16, payload []byte, fcs bool) []byte {
+	b := make([]byte, headerLen, headerLen+len(payload)+fcsLen)
+	copy(b[0:6], testMAC)
+	copy(b[6:12], peerMAC)
+	binary.BigEndian.PutUint16(b[12:14], typ)
+	b = append(b, payload...)
+
+	if fcs {
+		b = append(b, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-fcsLen:], crc32.ChecksumIEEE(b[:len(b)-fcsLen]))
+	}
+	return b
+}
+
+func TestUnmarshalAuto(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xaa}, 46)
+	arp := uint16(ethernet.EtherTypeARP)
+
+	corrupt := rawFrame(arp, payload, true)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	// A frame tagged with VLAN 10, followed by its FCS.
+	tagged := append([]byte(nil), testMAC...)
+	tagged = append(tagged, peerMAC...)
+	tagged = append(tagged, 0x81, 0x00, 0x00, 0x0a, 0x08, 0x06)
+	tagged = append(tagged, payload...)
+	tagged = append(tagged, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(tagged[len(tagged)-fcsLen:], crc32.ChecksumIEEE(tagged[:len(tagged)-fcsLen]))
+
+	tests := []struct {
+		name    string
+		b       []byte
+		fcs     bool
+		payload []byte
+		vlan    bool
+		ok      bool
+	}{
+		{
+			name:    "no FCS",
+			b:       rawFrame(arp, payload, false),
+			payload: payload,
+			ok:      true,
+		},
+		{
+			name:    "FCS",
+			b:       rawFrame(arp, payload, true),
+			fcs:     true,
+			payload: payload,
+			ok:      true,
+		},
+		{
+			name:    "VLAN tag and FCS",
+			b:       tagged,
+			fcs:     true,
+			payload: payload,
+			vlan:    true,
+			ok:      true,
+		},
+		{
+			// The checksum bytes are kept as part of the payload.
+			name:    "corrupted FCS",
+			b:       corrupt,
+			payload: corrupt[headerLen:],
+			ok:      true,
+		},
+		{
+			// Too short to carry an FCS, so all 3 bytes are payload.
+			name:    "short payload",
+			b:       rawFrame(arp, []byte{1, 2, 3}, false),
+			payload: []byte{1, 2, 3},
+			ok:      true,
+		},
+		{
+			name: "truncated header",
+			b:    rawFrame(arp, nil, false)[:headerLen-1],
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, fcs, err := UnmarshalAuto(tt.b)
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("expected an error, but none occurred")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+
+			if fcs != tt.fcs {
+				t.Fatalf("FCS detected: %v, want %v", fcs, tt.fcs)
+			}
+			if f.EtherType != ethernet.EtherTypeARP {
+				t.Fatalf("unexpected EtherType: %#04x", uint16(f.EtherType))
+			}
+			if (f.VLAN != nil) != tt.vlan {
+				t.Fatalf("unexpected VLAN tag: %+v", f.VLAN)
+			}
+			if !bytes.Equal(tt.payload, f.Payload) {
+				t.Fatalf("unexpected payload:\n- want: %x\n-  got: %x", tt.payload, f.Payload)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAutoEtherTypeBoundary(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xaa}, 46)
+
+	tests := []struct {
+		name string
+		typ  uint16
+		ii   bool
+	}{
+		{name: "802.3 at 1535", typ: 1535},
+		{name: "Ethernet II at 1536", typ: 1536, ii: true},
+	}
+
+	for _, tt := range tests {
+		for _, fcs := range []bool{false, true} {
+			name := tt.name
+			if fcs {
+				name += " with FCS"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				f, gotFCS, err := UnmarshalAuto(rawFrame(tt.typ, payload, fcs))
+				if err != nil {
+					t.Fatalf("failed to unmarshal: %v", err)
+				}
+				if gotFCS != fcs {
+					t.Fatalf("FCS detected: %v, want %v", gotFCS, fcs)
+				}
+				if uint16(f.EtherType) != tt.typ {
+					t.Fatalf("unexpected EtherType field: %#04x", uint16(f.EtherType))
+				}
+				if !bytes.Equal(f.Payload, payload) {
+					t.Fatalf("unexpected payload: %x", f.Payload)
+				}
+				if got := IsEthernetII(f); got != tt.ii {
+					t.Fatalf("IsEthernetII = %v, want %v", got, tt.ii)
+				}
+
+				// The 802.3 length of 1535 exceeds the payload.
+				if _, ok := IEEE8023Payload(f); ok {
+					t.Fatal("IEEE8023Payload returned a payload")
+				}
+			})
+		}
+	}
+}
+
+func TestIEEE8023PayloadShorterLength(t *testing.T) {
+	// A 3 byte LLC header, padded to the minimum payload of 46 bytes.
+	llc := []byte{0x42, 0x42, 0x03}
+	padded := append(append([]byte(nil), llc...), make([]byte, 46-len(llc))...)
+
+	for _, fcs := range []bool{false, true} {
+		f, gotFCS, err := UnmarshalAuto(rawFrame(uint16(len(llc)), padded, fcs))
+		if err != nil {
+			t.Fatalf("failed to unmarshal: %v", err)
+		}
+		if gotFCS != fcs {
+			t.Fatalf("FCS detected: %v, want %v", gotFCS, fcs)
+		}
+		if IsEthernetII(f) {
+			t.Fatal("802.3 frame reported as Ethernet II")
+		}
+		if len(f.Payload) != len(padded) {
+			t.Fatalf("unexpected padded payload length: %d", len(f.Payload))
+		}
+
+		p, ok := IEEE8023Payload(f)
+		if !ok {
+			t.Fatal("IEEE8023Payload returned no payload")
+		}
+		if !bytes.Equal(p, llc) {
+			t.Fatalf("unexpected 802.3 payload:\n- want: %x\n-  got: %x", llc, p)
+		}
+	}
+}
@@ -6,4 +6,6 @@ require (
 	github.com/pefish/go-ethernet v0.0.1
 	github.com/pefish/go-net-arp v0.0.4
 	github.com/pefish/go-net-raw v0.0.1
+	golang.org/x/net v0.0.0-20200222033325-078779b8f2d8
+	golang.org/x/sys v0.0.0-20200219091948-cb0a6d8edb6c
 )
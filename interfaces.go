@@ -0,0 +1,138 @@
+package arp
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInterfaceDown is returned by Read when the WithInterfaceDownError
+	// option is in effect and the Client's interface goes down. It is also
+	// the reason interfaces which are not up are unusable.
+	ErrInterfaceDown = errors.New("interface is down")
+
+	// errNoARP is returned for interfaces which do not use ARP, such as
+	// loopback and point-to-point interfaces.
+	errNoARP = errors.New("interface does not use ARP")
+
+	// errNoEthernetAddr is returned for interfaces without an Ethernet
+	// hardware address.
+	errNoEthernetAddr = errors.New("interface has no Ethernet hardware address")
+)
+
+// Interfaces returns the network interfaces of the system which can be
+// used with Dial: interfaces which are up, are neither loopback nor
+// point-to-point, and have a 6 byte Ethernet hardware address.
+func Interfaces() ([]net.Interface, error) {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var usable []net.Interface
+	for _, ifi := range ifis {
+		if checkInterface(&ifi) == nil {
+			usable = append(usable, ifi)
+		}
+	}
+
+	return usable, nil
+}
+
+// checkInterface returns an error if ifi cannot be used for ARP.
+func checkInterface(ifi *net.Interface) error {
+	switch {
+	case ifi.Flags&net.FlagUp == 0:
+		return ErrInterfaceDown
+	case ifi.Flags&(net.FlagLoopback|net.FlagPointToPoint) != 0:
+		return errNoARP
+	case len(ifi.HardwareAddr) != ethernetAddrLen:
+		return errNoEthernetAddr
+	}
+
+	return nil
+}
+
+// A DialAllError is returned by DialAll when some interfaces could not be
+// dialed. Each error is an *OpError naming the interface.
+type DialAllError struct {
+	Errors []error
+}
+
+// Error implements error.
+func (e *DialAllError) Error() string {
+	ss := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		ss = append(ss, err.Error())
+	}
+
+	return "failed to dial some interfaces: " + strings.Join(ss, "; ")
+}
+
+// DialAll dials every interface returned by Interfaces, with at most
+// maxConcurrent Dials in flight so a host with many interfaces does not
+// momentarily exhaust its file descriptor limit. A maxConcurrent of less
+// than 1 is treated as 1. opts are passed to each Dial.
+//
+// DialAll returns the Clients which were created successfully, in the
+// order of Interfaces, even if some interfaces failed. Failures are
+// reported together in a *DialAllError.
+func DialAll(maxConcurrent int, opts ...Option) ([]*Client, error) {
+	ifis, err := Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+
+	var (
+		clients = make([]*Client, len(ifis))
+		errs    = make([]error, len(ifis))
+		sem     = make(chan struct{}, maxConcurrent)
+		wg      sync.WaitGroup
+	)
+
+	for i := range ifis {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			c, err := Dial(&ifis[i], opts...)
+			if err != nil {
+				errs[i] = &OpError{
+					Op:        "dial",
+					Interface: ifis[i].Name,
+					Err:       err,
+				}
+				return
+			}
+			clients[i] = c
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		ok     []*Client
+		failed []error
+	)
+	for i := range ifis {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
+		}
+		ok = append(ok, clients[i])
+	}
+
+	if len(failed) > 0 {
+		return ok, &DialAllError{Errors: failed}
+	}
+	return ok, nil
+}
@@ -0,0 +1,63 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+)
+
+// An InventoryDiff describes the differences between two inventory
+// snapshots, such as those returned by ResolveMany. Each map is keyed by
+// the IP address in string form, like the snapshots themselves.
+type InventoryDiff struct {
+	// Added holds addresses present only in the new snapshot.
+	Added map[string]net.HardwareAddr
+
+	// Removed holds addresses present only in the old snapshot.
+	Removed map[string]net.HardwareAddr
+
+	// Changed holds addresses present in both snapshots with a different
+	// hardware address. A change may be legitimate, such as a replaced
+	// network card, but may also indicate ARP spoofing.
+	Changed map[string]InventoryChange
+}
+
+// An InventoryChange is the old and new hardware address of an IP address
+// whose binding changed between two inventory snapshots.
+type InventoryChange struct {
+	Old, New net.HardwareAddr
+}
+
+// Empty reports whether d contains no differences.
+func (d InventoryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffInventory compares two inventory snapshots keyed by IP address in
+// string form, and reports which addresses were added, removed, or are
+// bound to a different hardware address. Either snapshot may be nil. The
+// maps of the returned InventoryDiff are never nil.
+func DiffInventory(old, new map[string]net.HardwareAddr) InventoryDiff {
+	d := InventoryDiff{
+		Added:   make(map[string]net.HardwareAddr),
+		Removed: make(map[string]net.HardwareAddr),
+		Changed: make(map[string]InventoryChange),
+	}
+
+	for ip, mac := range new {
+		prev, ok := old[ip]
+		switch {
+		case !ok:
+			d.Added[ip] = mac
+		case !bytes.Equal(prev, mac):
+			d.Changed[ip] = InventoryChange{Old: prev, New: mac}
+		}
+	}
+
+	for ip, mac := range old {
+		if _, ok := new[ip]; !ok {
+			d.Removed[ip] = mac
+		}
+	}
+
+	return d
+}
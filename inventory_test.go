@@ -0,0 +1,107 @@
+package arp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDiffInventory(t *testing.T) {
+	mustMAC := func(s string) net.HardwareAddr {
+		mac, err := net.ParseMAC(s)
+		if err != nil {
+			t.Fatalf("failed to parse MAC: %v", err)
+		}
+		return mac
+	}
+
+	var (
+		macA = mustMAC("02:00:00:00:00:0a")
+		macB = mustMAC("02:00:00:00:00:0b")
+	)
+
+	tests := []struct {
+		name     string
+		old, new map[string]net.HardwareAddr
+		want     InventoryDiff
+	}{
+		{
+			name: "nil maps",
+		},
+		{
+			name: "nil old",
+			new:  map[string]net.HardwareAddr{"192.0.2.1": macA},
+			want: InventoryDiff{
+				Added: map[string]net.HardwareAddr{"192.0.2.1": macA},
+			},
+		},
+		{
+			name: "nil new",
+			old:  map[string]net.HardwareAddr{"192.0.2.1": macA},
+			want: InventoryDiff{
+				Removed: map[string]net.HardwareAddr{"192.0.2.1": macA},
+			},
+		},
+		{
+			name: "unchanged",
+			old:  map[string]net.HardwareAddr{"192.0.2.1": macA},
+			new:  map[string]net.HardwareAddr{"192.0.2.1": macA},
+		},
+		{
+			name: "case and format only",
+			old:  map[string]net.HardwareAddr{"192.0.2.1": mustMAC("02:00:00:00:00:AA")},
+			new:  map[string]net.HardwareAddr{"192.0.2.1": mustMAC("02-00-00-00-00-aa")},
+		},
+		{
+			name: "dotted format",
+			old:  map[string]net.HardwareAddr{"192.0.2.1": mustMAC("0200.0000.000a")},
+			new:  map[string]net.HardwareAddr{"192.0.2.1": macA},
+		},
+		{
+			name: "added removed and changed",
+			old: map[string]net.HardwareAddr{
+				"192.0.2.1": macA,
+				"192.0.2.2": macA,
+				"192.0.2.3": macB,
+			},
+			new: map[string]net.HardwareAddr{
+				"192.0.2.1": macA,
+				"192.0.2.3": macA,
+				"192.0.2.4": macB,
+			},
+			want: InventoryDiff{
+				Added:   map[string]net.HardwareAddr{"192.0.2.4": macB},
+				Removed: map[string]net.HardwareAddr{"192.0.2.2": macA},
+				Changed: map[string]InventoryChange{
+					"192.0.2.3": {Old: macB, New: macA},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The maps of the result are never nil.
+			want := tt.want
+			if want.Added == nil {
+				want.Added = map[string]net.HardwareAddr{}
+			}
+			if want.Removed == nil {
+				want.Removed = map[string]net.HardwareAddr{}
+			}
+			if want.Changed == nil {
+				want.Changed = map[string]InventoryChange{}
+			}
+
+			got := DiffInventory(tt.old, tt.new)
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected diff:\n- want: %+v\n-  got: %+v", want, got)
+			}
+
+			empty := len(want.Added) == 0 && len(want.Removed) == 0 && len(want.Changed) == 0
+			if got.Empty() != empty {
+				t.Fatalf("Empty() = %v, want %v", got.Empty(), empty)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+)
+
+// IPsEqual reports whether a and b are the same IP address. IPv4 addresses
+// are normalized to their 4 byte form before comparing, so an address
+// parsed by net.ParseIP (16 bytes) matches the same address decoded from
+// an ARP packet (4 bytes). Nil or otherwise invalid addresses are never
+// equal.
+func IPsEqual(a, b net.IP) bool {
+	a, b = normalizeIP(a), normalizeIP(b)
+	if a == nil || b == nil {
+		return false
+	}
+
+	return bytes.Equal(a, b)
+}
+
+// normalizeIP returns the 4 byte form of ip if it is an IPv4 address, ip
+// itself if it is a 16 byte IPv6 address, and nil otherwise.
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	if len(ip) == net.IPv6len {
+		return ip
+	}
+
+	return nil
+}
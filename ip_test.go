@@ -0,0 +1,127 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/pefish/go-net-arp"
+)
+
+func TestIPsEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b net.IP
+		ok   bool
+	}{
+		{
+			name: "16 and 4 bytes",
+			a:    net.IPv4(192, 0, 2, 1),
+			b:    net.IPv4(192, 0, 2, 1).To4(),
+			ok:   true,
+		},
+		{
+			name: "4 and 4 bytes",
+			a:    net.IPv4(192, 0, 2, 1).To4(),
+			b:    net.IPv4(192, 0, 2, 1).To4(),
+			ok:   true,
+		},
+		{
+			name: "different",
+			a:    net.IPv4(192, 0, 2, 1),
+			b:    net.IPv4(192, 0, 2, 2).To4(),
+		},
+		{
+			name: "IPv6",
+			a:    net.ParseIP("2001:db8::1"),
+			b:    net.ParseIP("2001:db8::1"),
+			ok:   true,
+		},
+		{
+			name: "IPv4 and IPv6",
+			a:    net.IPv4(192, 0, 2, 1),
+			b:    net.ParseIP("2001:db8::1"),
+		},
+		{
+			name: "nil",
+			a:    nil,
+			b:    nil,
+		},
+		{
+			name: "invalid",
+			a:    net.IP{192, 0, 2},
+			b:    net.IP{192, 0, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IPsEqual(tt.a, tt.b); got != tt.ok {
+				t.Fatalf("IPsEqual(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.ok)
+			}
+		})
+	}
+}
+
+// ipForms are the 16 and 4 byte forms of the same IPv4 address, which must
+// be treated alike.
+func ipForms(ip net.IP) []struct {
+	name string
+	ip   net.IP
+} {
+	return []struct {
+		name string
+		ip   net.IP
+	}{
+		{name: "16 bytes", ip: ip.To16()},
+		{name: "4 bytes", ip: ip.To4()},
+	}
+}
+
+func TestResolveIPForms(t *testing.T) {
+	for _, tt := range ipForms(peerIP) {
+		t.Run(tt.name, func(t *testing.T) {
+			c, conn := testClient(t)
+			defer c.Close()
+
+			conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+
+			mac, err := c.Resolve(tt.ip)
+			if err != nil {
+				t.Fatalf("failed to resolve: %v", err)
+			}
+			if !bytes.Equal(mac, peerMAC) {
+				t.Fatalf("unexpected hardware address: %s", mac)
+			}
+
+			p := nextWritten(t, conn)
+			if p.Operation != net_arp.OperationRequest || !IPsEqual(p.TargetIP, peerIP) {
+				t.Fatalf("unexpected request: %+v", p)
+			}
+		})
+	}
+}
+
+func TestReplyIPForms(t *testing.T) {
+	req, err := net_arp.NewPacket(net_arp.OperationRequest, peerMAC, peerIP, make(net.HardwareAddr, 6), testIP)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	for _, tt := range ipForms(testIP) {
+		t.Run(tt.name, func(t *testing.T) {
+			c, conn := testClient(t)
+			defer c.Close()
+
+			if err := c.Reply(req, testMAC, tt.ip); err != nil {
+				t.Fatalf("failed to reply: %v", err)
+			}
+
+			p := nextWritten(t, conn)
+			if p.Operation != net_arp.OperationReply || !bytes.Equal(p.SenderIP, testIP) ||
+				!bytes.Equal(p.TargetIP, peerIP) || !bytes.Equal(p.SenderHardwareAddr, testMAC) {
+				t.Fatalf("unexpected reply: %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package arp
+
+// LearnFromTraffic passively learns bindings from the sender addresses of
+// every ARP packet observed on the segment, including requests, replies
+// and gratuitous announcements, without sending anything. Each binding is
+// stored in the Client's Cache, and is emitted on the returned channel
+// when it is new or its hardware address changed. Repeated observations of
+// a known binding only update its timestamp.
+//
+// Packets with an unspecified sender IPv4 address, such as RFC 5227
+// probes, carry no binding and are ignored.
+//
+// LearnFromTraffic is built on Monitor, and the returned channel is closed
+// under the same conditions. Call the returned function to stop learning.
+func (c *Client) LearnFromTraffic() (<-chan Binding, func()) {
+	bindings := make(chan Binding, monitorBuffer)
+	return bindings, c.monitorChan(func(ev ARPEvent, quit <-chan struct{}) {
+		p := ev.Packet
+		if ip4 := p.SenderIP.To4(); ip4 == nil || ip4.IsUnspecified() {
+			return
+		}
+
+		if !c.cache.Store(p.SenderIP, p.SenderHardwareAddr, ev.Time) {
+			return
+		}
+
+		select {
+		case bindings <- Binding{
+			IP:           p.SenderIP,
+			HardwareAddr: p.SenderHardwareAddr,
+			Seen:         ev.Time,
+		}:
+		case <-quit:
+		}
+	}, func() { close(bindings) })
+}
@@ -0,0 +1,160 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+)
+
+// A linkUpdate is a change of the state of a network interface, reported by
+// a linkMonitor.
+type linkUpdate struct {
+	// Up is true if the interface is administratively up and has carrier.
+	Up bool
+
+	// HardwareAddr is the current hardware address of the interface, if
+	// known.
+	HardwareAddr net.HardwareAddr
+}
+
+// A linkMonitor reports changes of the state of a network interface.
+type linkMonitor interface {
+	// Updates returns a channel which receives an update whenever the
+	// interface changes, and which is closed when the monitor is closed or
+	// fails.
+	Updates() <-chan linkUpdate
+
+	// Close stops the monitor.
+	Close() error
+}
+
+// WithHardwareAddrChange sets a function which is called when the hardware
+// address of the Client's interface changes, for example when a bonding
+// failover promotes another slave or an administrator sets a new address.
+// fn is called from a background goroutine with the previous and the new
+// address, and must not block for long.
+//
+// The Client reads the hardware address of its interface once, when it is
+// created, and uses it as the source of every frame it sends. Unless
+// WithHardwareAddrAutoUpdate is also used, it keeps doing so after a change,
+// and fn is only a notification.
+//
+// Changes are detected by a link monitor, which uses netlink on Linux, and
+// polls the interface on other platforms. If the monitor cannot be started,
+// a warning is written to the Logger, see Capabilities.
+func WithHardwareAddrChange(fn func(old, new net.HardwareAddr)) Option {
+	return func(cfg *config) error {
+		cfg.onHardwareAddrChange = fn
+		return nil
+	}
+}
+
+// WithHardwareAddrAutoUpdate makes the Client follow changes of the hardware
+// address of its interface, so frames sent after a change, and the value
+// returned by HardwareAddr, use the new address. This is recommended for
+// long-lived Clients on bonded interfaces. It is opt-in, since an address
+// changing under a running Client can surprise callers which compare
+// packets against the address they saw earlier. It may be combined with
+// WithHardwareAddrChange to be notified of each update.
+func WithHardwareAddrAutoUpdate() Option {
+	return func(cfg *config) error {
+		cfg.hardwareAddrAutoUpdate = true
+		return nil
+	}
+}
+
+// WithInterfaceDownError makes Read, and the methods built on it, fail
+// promptly with an *OpError wrapping ErrInterfaceDown when the link monitor
+// reports that the Client's interface went administratively down or lost
+// carrier, rather than blocking until the read deadline. A pending read is
+// interrupted, and reads keep failing until the interface is up again, so
+// agents can react to link loss immediately. By default, link state is
+// ignored and reads simply time out.
+//
+// Like WithHardwareAddrChange, this requires a link monitor; if it cannot
+// be started, Read keeps the default behavior.
+func WithInterfaceDownError() Option {
+	return func(cfg *config) error {
+		cfg.interfaceDownError = true
+		return nil
+	}
+}
+
+// wantsLinkMonitor reports whether cfg enables a feature which requires a
+// link monitor.
+func (cfg *config) wantsLinkMonitor() bool {
+	return cfg.onHardwareAddrChange != nil || cfg.hardwareAddrAutoUpdate || cfg.interfaceDownError
+}
+
+// checkLink returns ErrInterfaceDown if the interface is reported down and
+// WithInterfaceDownError is in effect, and err otherwise. Read uses it to
+// replace the timeout caused by the interruption of a read.
+func (c *Client) checkLink(err error) error {
+	if !c.cfg.interfaceDownError {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return ErrInterfaceDown
+	}
+	return err
+}
+
+// setLinkDown records whether the interface is down, interrupting pending
+// reads when it goes down and restoring the read deadline when it comes
+// back up.
+func (c *Client) setLinkDown(down bool) {
+	c.mu.Lock()
+	changed := c.down != down
+	c.down = down
+	c.mu.Unlock()
+
+	if changed {
+		c.restoreReadDeadline()
+	}
+}
+
+// startLinkMonitor starts the link monitor for the Client's interface if
+// it is required, and reports whether it is running.
+func (c *Client) startLinkMonitor() bool {
+	if !c.cfg.wantsLinkMonitor() {
+		return false
+	}
+
+	m, err := c.cfg.linkMonitor(c.ifi)
+	if err != nil {
+		c.cfg.logger.Printf("%s: link monitor not started: %v", c.name, err)
+		return false
+	}
+
+	c.link = m
+	go c.watchLink(m.Updates())
+	return true
+}
+
+// watchLink applies link updates to the Client until updates is closed.
+func (c *Client) watchLink(updates <-chan linkUpdate) {
+	last := c.HardwareAddr()
+	for u := range updates {
+		if c.cfg.interfaceDownError {
+			c.setLinkDown(!u.Up)
+		}
+
+		if len(u.HardwareAddr) == 0 || bytes.Equal(u.HardwareAddr, last) {
+			continue
+		}
+
+		old := last
+		last = u.HardwareAddr
+		if c.cfg.hardwareAddrAutoUpdate {
+			c.mu.Lock()
+			c.mac = u.HardwareAddr
+			c.mu.Unlock()
+		}
+
+		if fn := c.cfg.onHardwareAddrChange; fn != nil {
+			fn(old, u.HardwareAddr)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+//go:build linux
+// +build linux
+
+package arp
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// newLinkMonitor creates a linkMonitor which subscribes to netlink link
+// notifications for ifi.
+func newLinkMonitor(ifi *net.Interface) (linkMonitor, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK,
+	}); err != nil {
+		_ = unix.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	// Using a non-blocking file lets the runtime poller unblock a pending
+	// read when the monitor is closed.
+	m := &netlinkMonitor{
+		f:       os.NewFile(uintptr(fd), "netlink"),
+		updates: make(chan linkUpdate),
+		stop:    make(chan struct{}),
+	}
+	go m.read(int32(ifi.Index))
+	return m, nil
+}
+
+// A netlinkMonitor is a linkMonitor which receives rtnetlink link
+// notifications.
+type netlinkMonitor struct {
+	f       *os.File
+	updates chan linkUpdate
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func (m *netlinkMonitor) Updates() <-chan linkUpdate { return m.updates }
+
+func (m *netlinkMonitor) Close() error {
+	var err error
+	m.once.Do(func() {
+		close(m.stop)
+		err = m.f.Close()
+	})
+	return err
+}
+
+// read sends an update for each notification about the interface with the
+// specified index, until the monitor is closed or reading fails.
+func (m *netlinkMonitor) read(index int32) {
+	defer close(m.updates)
+
+	buf := make([]byte, os.Getpagesize()*4)
+	for {
+		n, err := m.f.Read(buf)
+		if err != nil {
+			return
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range msgs {
+			u, ok := parseLinkMessage(msg, index)
+			if !ok {
+				continue
+			}
+
+			select {
+			case m.updates <- u:
+			case <-m.stop:
+				return
+			}
+		}
+	}
+}
+
+// parseLinkMessage decodes a link notification about the interface with the
+// specified index. It reports false for other messages.
+func parseLinkMessage(msg syscall.NetlinkMessage, index int32) (linkUpdate, bool) {
+	if len(msg.Data) < unix.SizeofIfInfomsg {
+		return linkUpdate{}, false
+	}
+
+	ifim := (*unix.IfInfomsg)(unsafe.Pointer(&msg.Data[0]))
+	if ifim.Index != index {
+		return linkUpdate{}, false
+	}
+
+	switch msg.Header.Type {
+	case unix.RTM_NEWLINK:
+	case unix.RTM_DELLINK:
+		// A removed interface is reported as down.
+		return linkUpdate{}, true
+	default:
+		return linkUpdate{}, false
+	}
+
+	const up = unix.IFF_UP | unix.IFF_LOWER_UP
+	u := linkUpdate{Up: ifim.Flags&up == up}
+
+	attrs, err := syscall.ParseNetlinkRouteAttr(&msg)
+	if err != nil {
+		return u, true
+	}
+	for _, a := range attrs {
+		if a.Attr.Type == unix.IFLA_ADDRESS {
+			u.HardwareAddr = net.HardwareAddr(append([]byte(nil), a.Value...))
+		}
+	}
+
+	return u, true
+}
@@ -0,0 +1,75 @@
+//go:build !linux
+// +build !linux
+
+package arp
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// linkPollInterval is how often the polling link monitor checks the
+// interface.
+const linkPollInterval = time.Second
+
+// newLinkMonitor creates a linkMonitor which polls the state of ifi, since
+// no link notifications are available on this platform.
+func newLinkMonitor(ifi *net.Interface) (linkMonitor, error) {
+	m := &pollLinkMonitor{
+		updates: make(chan linkUpdate),
+		stop:    make(chan struct{}),
+	}
+	go m.poll(ifi.Index, linkPollInterval)
+	return m, nil
+}
+
+// A pollLinkMonitor is a linkMonitor which polls an interface.
+type pollLinkMonitor struct {
+	updates chan linkUpdate
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func (m *pollLinkMonitor) Updates() <-chan linkUpdate { return m.updates }
+
+func (m *pollLinkMonitor) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+// poll sends an update whenever the interface with the specified index
+// changes, until the monitor is closed.
+func (m *pollLinkMonitor) poll(index int, interval time.Duration) {
+	defer close(m.updates)
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	var prev *linkUpdate
+	for {
+		select {
+		case <-tick.C:
+		case <-m.stop:
+			return
+		}
+
+		// An interface which disappeared is reported as down.
+		u := linkUpdate{}
+		if ifi, err := net.InterfaceByIndex(index); err == nil {
+			u.Up = ifi.Flags&net.FlagUp != 0
+			u.HardwareAddr = ifi.HardwareAddr
+		}
+
+		if prev != nil && prev.Up == u.Up && prev.HardwareAddr.String() == u.HardwareAddr.String() {
+			continue
+		}
+		prev = &u
+
+		select {
+		case m.updates <- u:
+		case <-m.stop:
+			return
+		}
+	}
+}
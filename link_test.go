@@ -0,0 +1,90 @@
+package arp
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// fakeLinkMonitor is a linkMonitor whose updates are sent by a test.
+type fakeLinkMonitor struct {
+	updates chan linkUpdate
+	once    sync.Once
+}
+
+func newFakeLinkMonitor() *fakeLinkMonitor {
+	return &fakeLinkMonitor{updates: make(chan linkUpdate)}
+}
+
+func (m *fakeLinkMonitor) Updates() <-chan linkUpdate { return m.updates }
+
+func (m *fakeLinkMonitor) Close() error {
+	m.once.Do(func() { close(m.updates) })
+	return nil
+}
+
+// withLinkMonitor makes the Client use m as its link monitor.
+func withLinkMonitor(m linkMonitor) Option {
+	return func(cfg *config) error {
+		cfg.linkMonitor = func(*net.Interface) (linkMonitor, error) {
+			return m, nil
+		}
+		return nil
+	}
+}
+
+func TestReadInterfaceDown(t *testing.T) {
+	m := newFakeLinkMonitor()
+	c, conn := testClient(t, WithInterfaceDownError(), withLinkMonitor(m))
+	if !c.startLinkMonitor() {
+		t.Fatal("link monitor not started")
+	}
+	defer c.Close()
+
+	// Without a deadline, only the link going down ends the pending read.
+	errC := make(chan error, 1)
+	go func() {
+		_, _, err := c.Read()
+		errC <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	m.updates <- linkUpdate{Up: false}
+
+	select {
+	case err := <-errC:
+		if !errors.Is(err, ErrInterfaceDown) {
+			t.Fatalf("expected ErrInterfaceDown, got: %v", err)
+		}
+		var oerr *OpError
+		if !errors.As(err, &oerr) {
+			t.Fatalf("expected an *OpError, got: %T", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending read was not interrupted when the link went down")
+	}
+
+	// Reads keep failing while the link is down.
+	if _, _, err := c.Read(); !errors.Is(err, ErrInterfaceDown) {
+		t.Fatalf("expected ErrInterfaceDown while down, got: %v", err)
+	}
+
+	// Once the link is up again, reads succeed. The updates are applied in
+	// order, so the first has been applied once the second is received.
+	m.updates <- linkUpdate{Up: true}
+	m.updates <- linkUpdate{Up: true}
+	conn.in <- arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, testMAC, testIP)
+
+	p, _, err := c.Read()
+	if err != nil {
+		t.Fatalf("failed to read after the link came up: %v", err)
+	}
+	if !bytes.Equal(p.SenderIP, peerIP) {
+		t.Fatalf("unexpected packet: %+v", p)
+	}
+}
@@ -0,0 +1,189 @@
+package arp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pefish/go-net-raw"
+)
+
+// loopbackBuffer is the number of frames a loopback Client can queue before
+// frames sent to it are dropped.
+const loopbackBuffer = 1024
+
+// errLoopbackClosed is returned by a loopback net.PacketConn after Close.
+var errLoopbackClosed = errors.New("loopback connection closed")
+
+// Loopback returns two Clients connected by an in-memory link, so frames
+// written by one are read by the other. This allows request and reply
+// exchanges, such as Resolve against a Node or Serve, to be tested end to
+// end without privileges or a network interface.
+//
+// The Clients use the interfaces "loop0" and "loop1", with the hardware
+// addresses 02:00:00:00:00:01 and 02:00:00:00:00:02, and the IPv4
+// addresses 192.0.2.1 and 192.0.2.2 in 192.0.2.0/24. Read deadlines are
+// honored as by a socket, and closing a Client does not close its peer.
+// Like a congested link, frames are dropped when the peer does not read
+// them fast enough.
+func Loopback() (a, b *Client) {
+	ca, cb := newLoopbackConn(), newLoopbackConn()
+	ca.peer, cb.peer = cb, ca
+
+	a = newLoopbackClient("loop0", 1, ca)
+	b = newLoopbackClient("loop1", 2, cb)
+	return a, b
+}
+
+// newLoopbackClient creates a loopback Client numbered n, using p.
+func newLoopbackClient(name string, n byte, p *loopbackConn) *Client {
+	ifi := &net.Interface{
+		Index:        int(n),
+		Name:         name,
+		MTU:          1500,
+		HardwareAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, n},
+		Flags:        net.FlagUp | net.FlagBroadcast,
+	}
+	addrs := []net.Addr{&net.IPNet{
+		IP:   net.IPv4(192, 0, 2, n),
+		Mask: net.CIDRMask(24, 32),
+	}}
+
+	// The default configuration and an IPv4 address are always valid.
+	cfg, _ := newConfig(nil)
+	c, _ := newClient(ifi, p, addrs, cfg)
+	return c
+}
+
+// A loopbackConn is one end of an in-memory link between two Clients.
+type loopbackConn struct {
+	in   chan []byte
+	peer *loopbackConn
+
+	// mu guards deadline and wake, which is closed to interrupt a pending
+	// read when the deadline changes.
+	mu       sync.Mutex
+	deadline time.Time
+	wake     chan struct{}
+
+	closed chan struct{}
+	once   sync.Once
+}
+
+// newLoopbackConn creates an unconnected loopbackConn.
+func newLoopbackConn() *loopbackConn {
+	return &loopbackConn{
+		in:     make(chan []byte, loopbackBuffer),
+		wake:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+// ReadFrom implements the net.PacketConn ReadFrom method.
+func (c *loopbackConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mu.Lock()
+		deadline, wake := c.deadline, c.wake
+		c.mu.Unlock()
+
+		var (
+			timer   *time.Timer
+			expired <-chan time.Time
+		)
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, nil, timeoutError{}
+			}
+
+			timer = time.NewTimer(d)
+			expired = timer.C
+		}
+
+		var (
+			frame []byte
+			err   error
+		)
+		select {
+		case frame = <-c.in:
+		case <-expired:
+			err = timeoutError{}
+		case <-wake:
+		case <-c.closed:
+			err = errLoopbackClosed
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		switch {
+		case err != nil:
+			return 0, nil, err
+		case frame == nil:
+			// The deadline changed, so wait again.
+			continue
+		}
+
+		addr := &net_raw.Addr{}
+		if len(frame) >= 12 {
+			addr.HardwareAddr = net.HardwareAddr(append([]byte(nil), frame[6:12]...))
+		}
+		return copy(b, frame), addr, nil
+	}
+}
+
+// WriteTo implements the net.PacketConn WriteTo method, delivering b to the
+// peer. addr is not used, since the link only has two ends.
+func (c *loopbackConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, errLoopbackClosed
+	default:
+	}
+
+	// Copy b with make, since appending an empty b to a nil slice yields
+	// nil, which ReadFrom would take for a wake-up rather than a frame.
+	frame := make([]byte, len(b))
+	copy(frame, b)
+
+	select {
+	case <-c.peer.closed:
+	case c.peer.in <- frame:
+	default:
+		// The peer's queue is full, so the frame is lost.
+	}
+	return len(b), nil
+}
+
+// Close implements the net.PacketConn Close method.
+func (c *loopbackConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// LocalAddr implements the net.PacketConn LocalAddr method.
+func (c *loopbackConn) LocalAddr() net.Addr {
+	return &net_raw.Addr{}
+}
+
+// SetDeadline implements the net.PacketConn SetDeadline method.
+func (c *loopbackConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements the net.PacketConn SetReadDeadline method.
+func (c *loopbackConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	close(c.wake)
+	c.wake = make(chan struct{})
+	c.mu.Unlock()
+	return nil
+}
+
+// SetWriteDeadline implements the net.PacketConn SetWriteDeadline method.
+// Writes never block, so it has no effect.
+func (c *loopbackConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
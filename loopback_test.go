@@ -0,0 +1,130 @@
+package arp
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+var (
+	loop0IP = net.IPv4(192, 0, 2, 1).To4()
+	loop1IP = net.IPv4(192, 0, 2, 2).To4()
+)
+
+func TestLoopbackEmptyWrite(t *testing.T) {
+	ca, cb := newLoopbackConn(), newLoopbackConn()
+	ca.peer, cb.peer = cb, ca
+
+	if _, err := ca.WriteTo([]byte{}, nil); err != nil {
+		t.Fatalf("failed to write empty frame: %v", err)
+	}
+
+	if err := cb.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	n, _, err := cb.ReadFrom(make([]byte, 1500))
+	if err != nil {
+		t.Fatalf("failed to read empty frame: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("unexpected frame length: %d", n)
+	}
+}
+
+// serveNode serves a Node owning ip on c until c is closed, which the
+// returned function does.
+func serveNode(t *testing.T, c *Client, ip net.IP) func() {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = NewNode(c, ip).Serve()
+	}()
+
+	return func() {
+		c.Close()
+		<-done
+	}
+}
+
+func TestLoopbackResolveServe(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer serveNode(t, b, loop1IP)()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	mac, err := a.ResolveContext(ctx, loop1IP)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	if !bytes.Equal(mac, b.HardwareAddr()) {
+		t.Fatalf("unexpected hardware address: %s", mac)
+	}
+}
+
+func TestLoopbackProbe(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer serveNode(t, b, loop1IP)()
+
+	// An RFC 5227 probe has an unspecified sender address, and is answered
+	// by the host which already uses the target address.
+	if err := a.RequestCustom(ARPFields{
+		SenderIP: net.IPv4zero,
+		TargetIP: loop1IP,
+	}); err != nil {
+		t.Fatalf("failed to send probe: %v", err)
+	}
+
+	if err := a.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	p, f, err := a.Read()
+	if err != nil {
+		t.Fatalf("failed to read probe reply: %v", err)
+	}
+
+	if p.Operation != net_arp.OperationReply || !bytes.Equal(p.SenderIP, loop1IP) ||
+		!bytes.Equal(p.SenderHardwareAddr, b.HardwareAddr()) || !p.TargetIP.IsUnspecified() {
+		t.Fatalf("unexpected probe reply: %+v", p)
+	}
+	if !bytes.Equal(f.Destination, a.HardwareAddr()) {
+		t.Fatalf("probe reply not addressed to the prober: %s", f.Destination)
+	}
+}
+
+func TestLoopbackAnnounce(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer b.Close()
+
+	bindings, stop := b.LearnFromTraffic()
+	defer stop()
+
+	// A gratuitous announcement carries the sender's own address as the
+	// target, and is learned by its peers.
+	if err := a.RequestCustom(ARPFields{TargetIP: loop0IP}); err != nil {
+		t.Fatalf("failed to send announcement: %v", err)
+	}
+
+	select {
+	case bd := <-bindings:
+		if !bytes.Equal(bd.IP, loop0IP) || !bytes.Equal(bd.HardwareAddr, a.HardwareAddr()) {
+			t.Fatalf("unexpected binding: %+v", bd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("announcement was not learned")
+	}
+
+	mac, ok := b.Cache().Lookup(loop0IP)
+	if !ok || !bytes.Equal(mac, a.HardwareAddr()) {
+		t.Fatalf("announcement not cached: %s, %v", mac, ok)
+	}
+}
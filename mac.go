@@ -0,0 +1,47 @@
+package arp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ethernetAddrLen is the length of an Ethernet (EUI-48) hardware address.
+const ethernetAddrLen = 6
+
+// ParseMAC parses s as a 6 byte Ethernet hardware address. In addition to
+// the colon (01:23:45:67:89:ab), dash (01-23-45-67-89-ab) and dotted
+// (0123.4567.89ab) forms accepted by net.ParseMAC, ParseMAC accepts 12 bare
+// hexadecimal digits (0123456789ab). Surrounding whitespace is ignored and
+// letters may be of either case.
+//
+// Unlike net.ParseMAC, addresses which are not 6 bytes long, such as EUI-64
+// or InfiniBand addresses, are rejected since they cannot be used for ARP
+// over Ethernet. The returned address is newly allocated, and its String
+// method yields the canonical lowercase colon form.
+func ParseMAC(s string) (net.HardwareAddr, error) {
+	in := strings.TrimSpace(s)
+	if in == "" {
+		return nil, fmt.Errorf("invalid MAC address %q: empty", s)
+	}
+
+	var (
+		mac net.HardwareAddr
+		err error
+	)
+	if strings.ContainsAny(in, ":-.") {
+		mac, err = net.ParseMAC(in)
+	} else {
+		mac, err = hex.DecodeString(in)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC address %q: want 6 hex octets separated by ':', '-' or '.'", s)
+	}
+
+	if len(mac) != ethernetAddrLen {
+		return nil, fmt.Errorf("invalid MAC address %q: %d bytes long, want %d byte Ethernet address", s, len(mac), ethernetAddrLen)
+	}
+
+	return mac, nil
+}
@@ -0,0 +1,59 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestParseMAC(t *testing.T) {
+	want := net.HardwareAddr{0x01, 0x23, 0x45, 0x67, 0x89, 0xab}
+
+	tests := []struct {
+		name string
+		s    string
+		ok   bool
+	}{
+		{name: "colon", s: "01:23:45:67:89:ab", ok: true},
+		{name: "dash", s: "01-23-45-67-89-ab", ok: true},
+		{name: "dotted", s: "0123.4567.89ab", ok: true},
+		{name: "bare", s: "0123456789ab", ok: true},
+		{name: "upper case", s: "01:23:45:67:89:AB", ok: true},
+		{name: "mixed case bare", s: "0123456789aB", ok: true},
+		{name: "whitespace", s: " \t01:23:45:67:89:ab\n", ok: true},
+		{name: "empty", s: ""},
+		{name: "only whitespace", s: "   "},
+		{name: "short", s: "01:23:45:67:89"},
+		{name: "short bare", s: "0123456789"},
+		{name: "odd bare", s: "0123456789a"},
+		{name: "long bare", s: "0123456789abcd"},
+		{name: "EUI-64", s: "01:23:45:67:89:ab:cd:ef"},
+		{name: "InfiniBand", s: "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01"},
+		{name: "bad digit", s: "01:23:45:67:89:ag"},
+		{name: "bad bare digit", s: "0123456789ag"},
+		{name: "mixed separators", s: "01:23-45:67:89:ab"},
+		{name: "inner whitespace", s: "01:23:45 :67:89:ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mac, err := ParseMAC(tt.s)
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", mac)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse MAC: %v", err)
+			}
+
+			if !bytes.Equal(want, mac) {
+				t.Fatalf("unexpected MAC: want %s, got %s", want, mac)
+			}
+			if got := mac.String(); got != want.String() {
+				t.Fatalf("unexpected canonical form: want %s, got %s", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package arp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// monitorBuffer is the capacity of channels returned by Monitor and the
+// passive helpers built on it.
+const monitorBuffer = 64
+
+// An ARPEvent is an ARP packet observed by Monitor.
+type ARPEvent struct {
+	// Packet and Frame are the decoded ARP packet and its ethernet frame.
+	Packet *net_arp.Packet
+	Frame  *ethernet.Frame
+
+	// Time is the time at which the packet was read.
+	Time time.Time
+
+	// Interface is the name of the network interface of the Client which
+	// read the packet.
+	Interface string
+}
+
+// Monitor passively reads ARP packets from the Client, and delivers them
+// as events on the returned channel until the returned stop function is
+// called or reading from the socket fails. In both cases the channel is
+// closed, and read errors are written to the Logger. Frames which cannot
+// be decoded, such as truncated or malformed packets sent by any host on
+// the segment, are written to the Logger and skipped. A read deadline set
+// on the Client also ends monitoring once it is reached.
+//
+// Monitor, and helpers such as LearnFromTraffic which are built on it,
+// share a single read loop, so several of them may be used at the same
+// time. Each event is delivered to all of them, and a consumer which does
+// not keep up delays the others. The read loop owns the read side of the
+// Client, so Read, Resolve and Serve must not be used while it is running.
+func (c *Client) Monitor() (<-chan ARPEvent, func()) {
+	events := make(chan ARPEvent, monitorBuffer)
+	return events, c.monitorChan(c.sendEvents(events), func() { close(events) })
+}
+
+// MonitorFor is like Monitor, but monitoring stops when ctx is done, which
+// closes the returned channel. Cancel ctx to stop monitoring early. Unlike a
+// read failure, the end of ctx is not logged.
+func (c *Client) MonitorFor(ctx context.Context) <-chan ARPEvent {
+	events := make(chan ARPEvent, monitorBuffer)
+	closed := make(chan struct{})
+	stop := c.monitorChan(c.sendEvents(events), func() {
+		close(events)
+		close(closed)
+	})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop()
+		case <-closed:
+		}
+	}()
+
+	return events
+}
+
+// MultiMonitor monitors several Clients, like Monitor, and merges their
+// events into the returned channel. The Interface field of each event
+// names the interface it was read from. Call the returned function to stop
+// monitoring all of them.
+//
+// A Client whose read fails stops contributing events, and its error is
+// written to its Logger, but the other Clients are monitored as usual. The
+// channel is closed once monitoring stopped for every Client.
+func MultiMonitor(clients ...*Client) (<-chan ARPEvent, func()) {
+	events := make(chan ARPEvent, monitorBuffer)
+
+	var wg sync.WaitGroup
+	wg.Add(len(clients))
+
+	stops := make([]func(), 0, len(clients))
+	for _, c := range clients {
+		stops = append(stops, c.monitorChan(c.sendEvents(events), wg.Done))
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	return events, func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// sendEvents returns a function for monitorChan which delivers events to
+// events, after de-duplicating them if WithDedup is in effect.
+func (c *Client) sendEvents(events chan<- ARPEvent) func(ev ARPEvent, quit <-chan struct{}) {
+	d := newDedup(c.cfg.dedup)
+	return func(ev ARPEvent, quit <-chan struct{}) {
+		if d.suppress(ev) {
+			return
+		}
+
+		select {
+		case events <- ev:
+		case <-quit:
+		}
+	}
+}
+
+// monitorChan subscribes fn to the shared read loop, and returns a function
+// which unsubscribes it. fn must return promptly once quit is closed.
+// closeFn is called exactly once, after fn has been called for the last
+// time, either when the returned function is called or when the read loop
+// fails.
+func (c *Client) monitorChan(fn func(ev ARPEvent, quit <-chan struct{}), closeFn func()) func() {
+	quit := make(chan struct{})
+	var once sync.Once
+	end := func() { once.Do(closeFn) }
+
+	sub := c.subscribe(func(ev ARPEvent) { fn(ev, quit) })
+
+	go func() {
+		select {
+		case <-sub.done:
+			c.cfg.logger.Printf("%s: monitor stopped: %v", c.name, sub.err)
+			end()
+		case <-quit:
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() {
+			close(quit)
+			c.unsubscribe(sub)
+			end()
+		})
+	}
+}
+
+// A subscription receives events from the shared read loop.
+type subscription struct {
+	fn func(ARPEvent)
+
+	// done is closed when the read loop fails, after err is set.
+	done chan struct{}
+	err  error
+}
+
+// monitor is the state of the read loop shared by Monitor subscribers.
+type monitor struct {
+	// life serializes starting and stopping the read loop.
+	life sync.Mutex
+
+	// mu guards the fields below, and is held while events are dispatched.
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// subscribe adds a subscription calling fn for each event, starting the
+// read loop if it is not running.
+func (c *Client) subscribe(fn func(ARPEvent)) *subscription {
+	sub := &subscription{
+		fn:   fn,
+		done: make(chan struct{}),
+	}
+
+	m := &c.mon
+	m.life.Lock()
+	defer m.life.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.subs == nil {
+		m.subs = make(map[*subscription]struct{})
+	}
+	m.subs[sub] = struct{}{}
+
+	if m.done == nil {
+		m.stop = make(chan struct{})
+		m.done = make(chan struct{})
+		go c.monitorLoop(m.stop, m.done)
+	}
+
+	return sub
+}
+
+// unsubscribe removes sub, and stops the read loop if sub was the last
+// subscription.
+func (c *Client) unsubscribe(sub *subscription) {
+	m := &c.mon
+	m.life.Lock()
+	defer m.life.Unlock()
+
+	m.mu.Lock()
+	delete(m.subs, sub)
+	last := len(m.subs) == 0 && m.done != nil
+	stop, done := m.stop, m.done
+	if last {
+		m.stop, m.done = nil, nil
+	}
+	m.mu.Unlock()
+
+	if !last {
+		return
+	}
+
+	// Unblock the pending read, wait for the loop to notice it was
+	// stopped, and then restore the caller's read deadline.
+	close(stop)
+	_ = c.p.SetReadDeadline(c.cfg.clock.Now())
+	<-done
+	c.restoreReadDeadline()
+}
+
+// monitorLoop reads packets and dispatches them to subscribers until stop
+// is closed or reading from the socket fails. Frames which cannot be
+// decoded are logged and skipped.
+func (c *Client) monitorLoop(stop, done chan struct{}) {
+	defer close(done)
+
+	m := &c.mon
+	for {
+		p, f, err := c.Read()
+		if isFrameError(err) {
+			// A malformed frame from any host must not end monitoring.
+			c.cfg.logger.Printf("%s: monitor: skipping frame: %v", c.name, err)
+			continue
+		}
+		if err != nil {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			// Fail all subscriptions, and allow a later subscribe to
+			// start a new loop.
+			m.mu.Lock()
+			for sub := range m.subs {
+				sub.err = err
+				close(sub.done)
+				delete(m.subs, sub)
+			}
+			m.stop, m.done = nil, nil
+			m.mu.Unlock()
+			return
+		}
+
+		ev := ARPEvent{
+			Packet:    p,
+			Frame:     f,
+			Time:      c.cfg.clock.Now(),
+			Interface: c.name,
+		}
+
+		m.mu.Lock()
+		for sub := range m.subs {
+			sub.fn(ev)
+		}
+		m.mu.Unlock()
+	}
+}
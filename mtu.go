@@ -0,0 +1,80 @@
+package arp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pefish/go-ethernet"
+)
+
+// vlanTagLen is the length of an IEEE 802.1Q VLAN tag.
+const vlanTagLen = 4
+
+// ErrFrameTooLarge is returned when a frame exceeds the MTU of the Client's
+// interface. Use errors.As with an *MTUError to retrieve the details.
+var ErrFrameTooLarge = errors.New("frame exceeds interface MTU")
+
+// An MTUError is returned by methods which send frames when the payload of
+// a frame, together with its VLAN tags, exceeds the MTU of the interface.
+type MTUError struct {
+	// MTU is the MTU of the interface.
+	MTU int
+
+	// PayloadLen is the length of the frame payload, and TagLen the length
+	// of its VLAN tags: 4 bytes for an 802.1Q tagged frame, and 8 bytes for
+	// an 802.1ad (QinQ) double tagged frame.
+	PayloadLen int
+	TagLen     int
+}
+
+// Error implements error.
+func (e *MTUError) Error() string {
+	return fmt.Sprintf("frame payload of %d bytes and %d bytes of VLAN tags exceed MTU of %d by %d bytes",
+		e.PayloadLen, e.TagLen, e.MTU, e.PayloadLen+e.TagLen-e.MTU)
+}
+
+// Is reports whether target is ErrFrameTooLarge.
+func (e *MTUError) Is(target error) bool {
+	return target == ErrFrameTooLarge
+}
+
+// WithMTUCheck sets whether frames are checked against the MTU of the
+// Client's interface before they are sent. The check is on by default, and
+// counts VLAN tags towards the MTU, since some strict interfaces drop
+// tagged frames which only fit untagged. A frame which does not fit fails
+// with an *MTUError, rather than with a platform specific error from the
+// socket, or not at all. Disable the check for interfaces which accept
+// tags on top of the MTU. The check is skipped if the interface does not
+// report an MTU.
+func WithMTUCheck(on bool) Option {
+	return func(cfg *config) error {
+		cfg.noMTUCheck = !on
+		return nil
+	}
+}
+
+// checkMTU returns an *MTUError if f does not fit the MTU of the Client's
+// interface.
+func (c *Client) checkMTU(f *ethernet.Frame) error {
+	mtu := c.ifi.MTU
+	if c.cfg.noMTUCheck || mtu <= 0 {
+		return nil
+	}
+
+	tags := 0
+	if f.ServiceVLAN != nil {
+		tags += vlanTagLen
+	}
+	if f.VLAN != nil {
+		tags += vlanTagLen
+	}
+
+	if len(f.Payload)+tags <= mtu {
+		return nil
+	}
+	return &MTUError{
+		MTU:        mtu,
+		PayloadLen: len(f.Payload),
+		TagLen:     tags,
+	}
+}
@@ -0,0 +1,86 @@
+package arp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pefish/go-ethernet"
+)
+
+func TestWriteFrameMTU(t *testing.T) {
+	const mtu = 1500
+
+	tests := []struct {
+		name  string
+		vlans func(f *ethernet.Frame)
+		tags  int
+	}{
+		{
+			name:  "untagged",
+			vlans: func(*ethernet.Frame) {},
+		},
+		{
+			name: "802.1Q",
+			vlans: func(f *ethernet.Frame) {
+				f.VLAN = &ethernet.VLAN{ID: 10}
+			},
+			tags: 4,
+		},
+		{
+			name: "QinQ",
+			vlans: func(f *ethernet.Frame) {
+				f.ServiceVLAN = &ethernet.VLAN{ID: 100}
+				f.VLAN = &ethernet.VLAN{ID: 10}
+			},
+			tags: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := func(n int) *ethernet.Frame {
+				f := &ethernet.Frame{
+					Destination: peerMAC,
+					Source:      testMAC,
+					EtherType:   ethernet.EtherTypeARP,
+					Payload:     make([]byte, n),
+				}
+				tt.vlans(f)
+				return f
+			}
+
+			c, conn := testClient(t)
+			defer c.Close()
+
+			// Exactly at the MTU, counting the tags, the frame is sent.
+			if err := c.WriteFrame(frame(mtu - tt.tags)); err != nil {
+				t.Fatalf("failed to write frame at the MTU: %v", err)
+			}
+			<-conn.out
+
+			// One byte more fails with an *MTUError.
+			err := c.WriteFrame(frame(mtu - tt.tags + 1))
+			if !errors.Is(err, ErrFrameTooLarge) {
+				t.Fatalf("expected ErrFrameTooLarge, got: %v", err)
+			}
+
+			var merr *MTUError
+			if !errors.As(err, &merr) {
+				t.Fatalf("expected an *MTUError, got: %T", err)
+			}
+			want := MTUError{MTU: mtu, PayloadLen: mtu - tt.tags + 1, TagLen: tt.tags}
+			if *merr != want {
+				t.Fatalf("unexpected MTUError:\n- want: %+v\n-  got: %+v", want, *merr)
+			}
+
+			// Without the check, the oversized frame is sent anyway.
+			nc, nconn := testClient(t, WithMTUCheck(false))
+			defer nc.Close()
+
+			if err := nc.WriteFrame(frame(mtu - tt.tags + 1)); err != nil {
+				t.Fatalf("failed to write frame with the MTU check disabled: %v", err)
+			}
+			<-nconn.out
+		})
+	}
+}
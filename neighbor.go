@@ -0,0 +1,55 @@
+package arp
+
+import (
+	"errors"
+	"net"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// ErrLearnSkipped is returned by ResolveAndLearn, together with the resolved
+// hardware address, when the kernel neighbor table cannot be updated on the
+// current platform.
+var ErrLearnSkipped = errors.New("kernel neighbor table update skipped: not supported on this platform")
+
+// SetNeighbor installs a binding of ip to mac for the Client's interface
+// in the kernel neighbor (ARP) table, replacing any existing entry. The
+// entry is created in the reachable state, so the kernel ages and
+// revalidates it like any entry it learned itself.
+//
+// SetNeighbor uses netlink and is only supported on Linux, where it
+// requires CAP_NET_ADMIN. On other platforms it returns ErrLearnSkipped.
+// Errors are returned as an *OpError.
+func (c *Client) SetNeighbor(ip net.IP, mac net.HardwareAddr) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return c.opError("set neighbor", net_arp.ErrInvalidIP)
+	}
+	if len(mac) != ethernetAddrLen {
+		return c.opError("set neighbor", net_arp.ErrInvalidHardwareAddr)
+	}
+
+	if err := setNeighbor(c.ifi.Index, ip4, mac); err != nil {
+		return c.opError("set neighbor", err)
+	}
+	return nil
+}
+
+// ResolveAndLearn resolves the hardware address of ip, like Resolve, and
+// then installs the binding in the kernel neighbor table with SetNeighbor,
+// so the host can use it right away. This is a convenience for
+// provisioning tools which discover a binding and then use it.
+//
+// If resolving fails, its error is returned. If only the learn step
+// fails, the resolved address is returned together with the error of
+// SetNeighbor, which wraps ErrLearnSkipped on platforms without netlink
+// support, so callers can treat it as non-fatal. The learn step requires
+// CAP_NET_ADMIN.
+func (c *Client) ResolveAndLearn(ip net.IP) (net.HardwareAddr, error) {
+	mac, err := c.Resolve(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return mac, c.SetNeighbor(ip, mac)
+}
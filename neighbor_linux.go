@@ -0,0 +1,101 @@
+//go:build linux
+// +build linux
+
+package arp
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// setNeighbor adds or replaces the neighbor table entry binding ip to mac on
+// the interface with the specified index, using rtnetlink.
+func setNeighbor(index int, ip net.IP, mac net.HardwareAddr) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer unix.Close(fd)
+
+	sa := &unix.SockaddrNetlink{Family: unix.AF_NETLINK}
+	if err := unix.Sendto(fd, neighborMessage(index, ip, mac), 0, sa); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+
+	buf := make([]byte, os.Getpagesize())
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return os.NewSyscallError("recvfrom", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+
+		for _, m := range msgs {
+			if m.Header.Type != unix.NLMSG_ERROR || len(m.Data) < 4 {
+				continue
+			}
+
+			// The acknowledgement carries a negated errno, or zero on
+			// success.
+			if errno := -*(*int32)(unsafe.Pointer(&m.Data[0])); errno != 0 {
+				return os.NewSyscallError("RTM_NEWNEIGH", syscall.Errno(errno))
+			}
+			return nil
+		}
+	}
+}
+
+// neighborMessage builds an RTM_NEWNEIGH request for setNeighbor, which asks
+// for an acknowledgement.
+func neighborMessage(index int, ip net.IP, mac net.HardwareAddr) []byte {
+	attrs := []struct {
+		typ  uint16
+		data []byte
+	}{
+		{typ: unix.NDA_DST, data: ip},
+		{typ: unix.NDA_LLADDR, data: mac},
+	}
+
+	size := unix.SizeofNlMsghdr + unix.SizeofNdMsg
+	for _, a := range attrs {
+		size += rtaAlign(unix.SizeofRtAttr + len(a.data))
+	}
+
+	b := make([]byte, size)
+	*(*unix.NlMsghdr)(unsafe.Pointer(&b[0])) = unix.NlMsghdr{
+		Len:   uint32(size),
+		Type:  unix.RTM_NEWNEIGH,
+		Flags: unix.NLM_F_REQUEST | unix.NLM_F_ACK | unix.NLM_F_CREATE | unix.NLM_F_REPLACE,
+		Seq:   1,
+	}
+	*(*unix.NdMsg)(unsafe.Pointer(&b[unix.SizeofNlMsghdr])) = unix.NdMsg{
+		Family:  unix.AF_INET,
+		Ifindex: int32(index),
+		State:   unix.NUD_REACHABLE,
+	}
+
+	n := unix.SizeofNlMsghdr + unix.SizeofNdMsg
+	for _, a := range attrs {
+		*(*unix.RtAttr)(unsafe.Pointer(&b[n])) = unix.RtAttr{
+			Len:  uint16(unix.SizeofRtAttr + len(a.data)),
+			Type: a.typ,
+		}
+		copy(b[n+unix.SizeofRtAttr:], a.data)
+		n += rtaAlign(unix.SizeofRtAttr + len(a.data))
+	}
+
+	return b
+}
+
+// rtaAlign rounds n up to the 4 byte alignment of netlink attributes.
+func rtaAlign(n int) int {
+	return (n + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
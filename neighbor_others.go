@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package arp
+
+import (
+	"net"
+)
+
+// setNeighbor is not supported on this platform.
+func setNeighbor(index int, ip net.IP, mac net.HardwareAddr) error {
+	return ErrLearnSkipped
+}
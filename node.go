@@ -0,0 +1,157 @@
+package arp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// ErrNodeStopped is returned by Node.Resolve when the Node's Serve loop
+// has returned.
+var ErrNodeStopped = errors.New("node is not serving")
+
+// A Node coordinates answering requests for owned IPv4 addresses and
+// resolving the hardware addresses of neighbors over a single Client.
+//
+// Only one goroutine may own the read side of a Client, so a Node performs
+// all reads from its Serve loop. Requests for owned addresses, including
+// RFC 5227 probes, are answered with the Client's hardware address, and
+// replies are delivered to pending Resolve calls.
+type Node struct {
+	c *Client
+
+	mu      sync.Mutex
+	owned   map[string]bool
+	pending map[string][]chan net.HardwareAddr
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewNode creates a Node which uses c, and answers requests for owned.
+func NewNode(c *Client, owned ...net.IP) *Node {
+	n := &Node{
+		c:       c,
+		owned:   make(map[string]bool),
+		pending: make(map[string][]chan net.HardwareAddr),
+		done:    make(chan struct{}),
+	}
+
+	for _, ip := range owned {
+		n.AddAddr(ip)
+	}
+
+	return n
+}
+
+// AddAddr begins answering requests for ip.
+func (n *Node) AddAddr(ip net.IP) {
+	n.mu.Lock()
+	n.owned[ip.String()] = true
+	n.mu.Unlock()
+}
+
+// RemoveAddr stops answering requests for ip.
+func (n *Node) RemoveAddr(ip net.IP) {
+	n.mu.Lock()
+	delete(n.owned, ip.String())
+	n.mu.Unlock()
+}
+
+// Serve reads from the Node's Client until reading from the socket fails,
+// and returns that error. Like Client.Serve, it skips frames which cannot
+// be decoded. Serve may only be called once. When it returns, pending and
+// future Resolve calls fail with ErrNodeStopped.
+func (n *Node) Serve() error {
+	defer n.once.Do(func() { close(n.done) })
+	return n.c.Serve(HandlerFunc(n.serveARP))
+}
+
+// Resolve sends a request for ip and waits until the Serve loop reads a
+// reply from it, or ctx is done. It is safe to call Resolve concurrently
+// from multiple goroutines while Serve is running.
+func (n *Node) Resolve(ctx context.Context, ip net.IP) (net.HardwareAddr, error) {
+	// Buffer the channel so the Serve loop never blocks on delivery.
+	ch := make(chan net.HardwareAddr, 1)
+	key := ip.String()
+
+	n.mu.Lock()
+	n.pending[key] = append(n.pending[key], ch)
+	n.mu.Unlock()
+	defer n.cancel(key, ch)
+
+	select {
+	case <-n.done:
+		return nil, ErrNodeStopped
+	default:
+	}
+
+	if err := n.c.Request(ip); err != nil {
+		return nil, err
+	}
+
+	select {
+	case mac := <-ch:
+		return mac, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.done:
+		return nil, ErrNodeStopped
+	}
+}
+
+// cancel removes ch from the waiters for key.
+func (n *Node) cancel(key string, ch chan net.HardwareAddr) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	chs := n.pending[key]
+	for i := range chs {
+		if chs[i] == ch {
+			chs = append(chs[:i], chs[i+1:]...)
+			break
+		}
+	}
+
+	if len(chs) == 0 {
+		delete(n.pending, key)
+		return
+	}
+	n.pending[key] = chs
+}
+
+// serveARP answers requests for owned addresses and delivers replies to
+// pending Resolve calls.
+func (n *Node) serveARP(c *Client, p *net_arp.Packet, f *ethernet.Frame) {
+	// Ignore anything we sent ourselves.
+	if bytes.Equal(p.SenderHardwareAddr, c.HardwareAddr()) {
+		return
+	}
+
+	switch p.Operation {
+	case net_arp.OperationRequest:
+		n.mu.Lock()
+		owned := n.owned[p.TargetIP.String()]
+		n.mu.Unlock()
+
+		if owned {
+			if err := c.Reply(p, c.HardwareAddr(), p.TargetIP); err != nil {
+				c.cfg.logger.Printf("failed to reply to %s for %s: %v", p.SenderHardwareAddr, p.TargetIP, err)
+			}
+		}
+	case net_arp.OperationReply:
+		n.mu.Lock()
+		chs := n.pending[p.SenderIP.String()]
+		delete(n.pending, p.SenderIP.String())
+		n.mu.Unlock()
+
+		for _, ch := range chs {
+			ch <- p.SenderHardwareAddr
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package arp
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"golang.org/x/net/bpf"
+)
+
+// DefaultDuplicateGrace is the default amount of time Resolve and ResolveAll
+// keep reading after the first reply, in order to catch a second host
+// racing to answer the same request.
+const DefaultDuplicateGrace = 100 * time.Millisecond
+
+var (
+	// errInvalidDuplicateGrace is returned when a negative duplicate reply
+	// grace window is configured.
+	errInvalidDuplicateGrace = errors.New("duplicate reply grace window must not be negative")
+
+	// errInvalidPriority is returned when a negative packet priority is
+	// configured.
+	errInvalidPriority = errors.New("packet priority must not be negative")
+
+	// errInvalidEtherType is returned when an EtherType which would be
+	// interpreted as an IEEE 802.3 length field is configured.
+	errInvalidEtherType = errors.New("EtherType must be at least 0x0600")
+
+	// errInvalidCacheTTL is returned when a negative cache TTL is
+	// configured.
+	errInvalidCacheTTL = errors.New("cache TTL must not be negative")
+
+	// errInvalidCacheMaxAge is returned when a negative cache maximum age
+	// is configured.
+	errInvalidCacheMaxAge = errors.New("cache maximum age must not be negative")
+
+	// errInvalidTimeout is returned when a negative default timeout is
+	// configured.
+	errInvalidTimeout = errors.New("default timeout must not be negative")
+
+	// errNilRand is returned when a nil random source is configured.
+	errNilRand = errors.New("random source must not be nil")
+
+	// errNilLogger is returned when a nil Logger is configured.
+	errNilLogger = errors.New("logger must not be nil")
+
+	// errInvalidReplyDelay is returned when a negative reply delay or
+	// jitter is configured.
+	errInvalidReplyDelay = errors.New("reply delay and jitter must not be negative")
+
+	// errInvalidSourceMAC is returned when a source hardware address which
+	// is not 6 bytes long is configured.
+	errInvalidSourceMAC = errors.New("source hardware address must be 6 bytes long")
+)
+
+// An Option configures a Client created by Dial or New.
+type Option func(cfg *config) error
+
+// config contains the settings which may be changed by passing Options to
+// Dial or New.
+type config struct {
+	duplicates     DuplicatePolicy
+	duplicateGrace time.Duration
+
+	etherType   ethernet.EtherType
+	cacheTTL    time.Duration
+	cacheMaxAge time.Duration
+	timeout     time.Duration
+	dedup       time.Duration
+
+	promiscuous bool
+	strict      bool
+	noMTUCheck  bool
+
+	// vlanFilter is nil unless WithVLANFilter was used.
+	vlanFilter      map[uint16]struct{}
+	vlanFilterOuter bool
+
+	// bpfFilter is nil unless WithBPFFilter was used.
+	bpfFilter []bpf.RawInstruction
+
+	// trustedClaimers is nil unless WithTrustedClaimers was used.
+	trustedClaimers map[string]struct{}
+
+	// priority is nil unless WithPacketPriority was used.
+	priority *int
+
+	// onCacheConflict is nil unless WithCacheConflict was used.
+	onCacheConflict func(ip net.IP, old, new net.HardwareAddr) bool
+
+	// replyDelay and replyJitter are the fixed and random parts of the
+	// delay before Reply sends a reply.
+	replyDelay  time.Duration
+	replyJitter time.Duration
+
+	clock Clock
+
+	// sourceIP and sourceMAC are nil unless WithSourceIP and WithSourceMAC
+	// were used.
+	sourceIP  net.IP
+	sourceMAC net.HardwareAddr
+
+	// rand is nil unless WithRand was used.
+	rand *rand.Rand
+
+	logger *log.Logger
+
+	// onHardwareAddrChange, hardwareAddrAutoUpdate and interfaceDownError
+	// configure how the Client reacts to link updates from linkMonitor.
+	onHardwareAddrChange   func(old, new net.HardwareAddr)
+	hardwareAddrAutoUpdate bool
+	interfaceDownError     bool
+	linkMonitor            func(ifi *net.Interface) (linkMonitor, error)
+}
+
+// newConfig applies opts on top of the default configuration.
+func newConfig(opts []Option) (config, error) {
+	cfg := config{
+		duplicates:     DuplicateFirst,
+		duplicateGrace: DefaultDuplicateGrace,
+		etherType:      protocolARP,
+		cacheTTL:       DefaultCacheTTL,
+		timeout:        DefaultTimeout,
+		logger:         log.New(os.Stderr, "arp: ", log.LstdFlags),
+		linkMonitor:    newLinkMonitor,
+		clock:          systemClock{},
+	}
+
+	for _, o := range opts {
+		if err := o(&cfg); err != nil {
+			return config{}, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// WithDuplicatePolicy sets how Resolve behaves when more than one host
+// answers a single request. The default is DuplicateFirst.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(cfg *config) error {
+		switch p {
+		case DuplicateFirst, DuplicateError:
+		default:
+			return errInvalidDuplicatePolicy
+		}
+
+		cfg.duplicates = p
+		return nil
+	}
+}
+
+// WithDuplicateGrace sets how long Resolve and ResolveAll keep reading after
+// the first reply to collect further answers. The default is
+// DefaultDuplicateGrace. It has no effect on Resolve when the duplicate
+// policy is DuplicateFirst.
+func WithDuplicateGrace(d time.Duration) Option {
+	return func(cfg *config) error {
+		if d < 0 {
+			return errInvalidDuplicateGrace
+		}
+
+		cfg.duplicateGrace = d
+		return nil
+	}
+}
+
+// WithLogger sets the Logger used to report non-fatal problems, such as an
+// option which is not supported on the current platform. By default,
+// messages are written to standard error.
+func WithLogger(l *log.Logger) Option {
+	return func(cfg *config) error {
+		if l == nil {
+			return errNilLogger
+		}
+
+		cfg.logger = l
+		return nil
+	}
+}
+
+// WithPacketPriority sets the socket priority (SO_PRIORITY) of the Client's
+// socket, so outgoing ARP frames are queued with skb priority p. This is
+// useful to keep ARP from being starved on congested links, or to validate
+// QoS behavior in a lab.
+//
+// This option is only supported on Linux, where values 0 through 6 may be
+// set by any user and higher values require CAP_NET_ADMIN. The priority
+// selects the egress queue of the qdisc, and when sending through a VLAN
+// device it is translated to an 802.1p PCP value using the device's
+// egress-qos-map. On other platforms, or when New is given a net.PacketConn
+// which does not implement syscall.Conn, the option is a no-op and a warning
+// is written to the Logger.
+func WithPacketPriority(p int) Option {
+	return func(cfg *config) error {
+		if p < 0 {
+			return errInvalidPriority
+		}
+
+		cfg.priority = &p
+		return nil
+	}
+}
+
+// minEtherType is the smallest value of an Ethernet II type field. Smaller
+// values are IEEE 802.3 length fields.
+const minEtherType = 0x0600
+
+// WithARPEtherType sets the EtherType used on frames sent by Request, Reply
+// and WriteTo, and the EtherType expected on frames returned by Read. The
+// default is ethernet.EtherTypeARP (0x0806). Some overlay and tunnel
+// encapsulations carry ARP packets under a different EtherType.
+//
+// et must be at least 0x0600 (1536), since smaller values denote an 802.3
+// length field. When using Dial, the socket is bound to et, so the kernel
+// only delivers frames of that type and standard ARP traffic is no longer
+// received. Frames read through a net.PacketConn passed to New are filtered
+// by Read instead. For VLAN tagged frames, et is matched against the
+// EtherType following the tags.
+func WithARPEtherType(et ethernet.EtherType) Option {
+	return func(cfg *config) error {
+		if et < minEtherType {
+			return errInvalidEtherType
+		}
+
+		cfg.etherType = et
+		return nil
+	}
+}
+
+// WithCacheTTL sets the TTL of the Client's Cache. The default is
+// DefaultCacheTTL, and zero means entries never expire.
+func WithCacheTTL(d time.Duration) Option {
+	return func(cfg *config) error {
+		if d < 0 {
+			return errInvalidCacheTTL
+		}
+
+		cfg.cacheTTL = d
+		return nil
+	}
+}
+
+// WithCacheMaxAge sets the maximum age of bindings in the Client's Cache,
+// after which they expire even if they are used constantly. See
+// Cache.SetMaxAge for how it differs from the TTL set with WithCacheTTL.
+// The default is zero, which means no maximum age.
+func WithCacheMaxAge(d time.Duration) Option {
+	return func(cfg *config) error {
+		if d < 0 {
+			return errInvalidCacheMaxAge
+		}
+
+		cfg.cacheMaxAge = d
+		return nil
+	}
+}
+
+// WithCacheConflict sets the conflict handler of the Client's Cache, which
+// decides whether a binding learned or resolved for an IPv4 address replaces
+// a different hardware address already cached for it. See
+// Cache.SetConflictHandler. By default, the latest binding wins.
+func WithCacheConflict(fn func(ip net.IP, old, new net.HardwareAddr) bool) Option {
+	return func(cfg *config) error {
+		cfg.onCacheConflict = fn
+		return nil
+	}
+}
+
+// WithDefaultTimeout sets how long Resolve and ResolveAll wait for a reply
+// when no read deadline is set on the Client. The default is
+// DefaultTimeout, which may be too short for high latency links such as
+// satellite or cellular. Zero disables the timeout, so Resolve blocks until
+// a reply arrives.
+//
+// An explicit deadline always wins: a read deadline set with SetDeadline or
+// SetReadDeadline, or the deadline of the context passed to ResolveContext,
+// is used instead of the default timeout.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(cfg *config) error {
+		if d < 0 {
+			return errInvalidTimeout
+		}
+
+		cfg.timeout = d
+		return nil
+	}
+}
+
+// WithPromiscuous enables promiscuous mode on the interface for the
+// lifetime of the Client's socket, so ARP replies addressed to other hosts
+// are received too. This is useful for passive monitoring on switched
+// segments with port mirroring. If promiscuous mode cannot be enabled, a
+// warning is written to the Logger, see Capabilities.
+func WithPromiscuous() Option {
+	return func(cfg *config) error {
+		cfg.promiscuous = true
+		return nil
+	}
+}
+
+// WithReplyDelay makes Reply wait before sending each reply, for a fixed
+// delay plus a random duration less than jitter, drawn from the source set
+// with WithRand. This makes a Node or ProxyTable a more realistic test
+// double for switches and hosts, so the timeout and retry logic of clients
+// under test is exercised against real reply latencies. The delay is timed
+// with the Clock set with WithClock, which keeps tests deterministic. The
+// default is no delay.
+//
+// Reply blocks for the delay. Since Serve calls handlers synchronously,
+// requests which arrive meanwhile are read once the reply has been sent, so
+// a delay longer than the interval between requests makes the responder
+// fall behind. The delay is not bounded by the read or write deadline of
+// the Client, and a client under test which gives up before the delay
+// elapses sees a timeout, as it would against a slow host.
+func WithReplyDelay(delay, jitter time.Duration) Option {
+	return func(cfg *config) error {
+		if delay < 0 || jitter < 0 {
+			return errInvalidReplyDelay
+		}
+
+		cfg.replyDelay = delay
+		cfg.replyJitter = jitter
+		return nil
+	}
+}
+
+// WithSourceIP sets the IPv4 address used as the sender address of the
+// Client's requests, instead of the first IPv4 address of the interface.
+// When it is set, Dial and New do not enumerate the addresses of the
+// interface at all, which is faster for tools which create many Clients,
+// and gives control on interfaces with many addresses.
+//
+// This is an advanced option: ip is used verbatim, without checking that it
+// is configured on the interface. Replies to requests from an address the
+// host does not own are still received by the Client, but other hosts may
+// learn a binding for ip which the host does not answer for.
+func WithSourceIP(ip net.IP) Option {
+	return func(cfg *config) error {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return errInvalidSourceIP
+		}
+
+		cfg.sourceIP = ip4
+		return nil
+	}
+}
+
+// WithSourceMAC sets the source address of the ethernet frames sent by
+// Request, Resolve, WriteTo and the methods built on them, instead of the
+// hardware address of the interface. This allows testing how devices react
+// to a specific requester, or MAC spoofing scenarios in a lab. The sender
+// hardware address inside the ARP packet is not changed, so replies are
+// still addressed to the interface; use RequestCustom to change it too.
+// Frames sent with WriteFrame, including replies sent by Reply, are not
+// affected.
+//
+// mac must be a 6 byte hardware address. Depending on its configuration,
+// the NIC, driver or kernel may drop frames with a spoofed source address,
+// or rewrite it. Switches with port security may also block the port.
+func WithSourceMAC(mac net.HardwareAddr) Option {
+	return func(cfg *config) error {
+		if len(mac) != ethernetAddrLen {
+			return errInvalidSourceMAC
+		}
+
+		cfg.sourceMAC = append(net.HardwareAddr(nil), mac...)
+		return nil
+	}
+}
+
+// WithRand sets the random source used for all randomized timing of the
+// Client, such as the pacing of ResolveMany sweeps and reply delay jitter,
+// which makes that timing reproducible in tests. By default each Client uses its own source seeded from the
+// current time, which also avoids contention on the global source of
+// math/rand. r is used under the Client's lock, so it must not be used
+// elsewhere concurrently.
+func WithRand(r *rand.Rand) Option {
+	return func(cfg *config) error {
+		if r == nil {
+			return errNilRand
+		}
+
+		cfg.rand = r
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package arp
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Constants from the libpcap file format, described at
+// https://wiki.wireshark.org/Development/LibpcapFileFormat.
+const (
+	// pcapMagic and pcapMagicNanos identify files with microsecond and
+	// nanosecond timestamps, in the byte order of the writer.
+	pcapMagic      = 0xa1b2c3d4
+	pcapMagicNanos = 0xa1b23c4d
+
+	pcapVersionMajor = 2
+	pcapVersionMinor = 4
+
+	// pcapLinkTypeEthernet is LINKTYPE_ETHERNET.
+	pcapLinkTypeEthernet = 1
+
+	// pcapHeaderLen and pcapRecordLen are the lengths of the global header
+	// and of each packet record header.
+	pcapHeaderLen = 24
+	pcapRecordLen = 16
+)
+
+// A pcapWriter writes ethernet frames to w in libpcap format, with
+// microsecond timestamps in little endian byte order.
+type pcapWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// newPcapWriter writes the pcap global header to w, and returns a
+// pcapWriter which writes packet records to it. snaplen is the maximum
+// number of bytes recorded for each frame.
+func newPcapWriter(w io.Writer, snaplen int) (*pcapWriter, error) {
+	b := make([]byte, pcapHeaderLen)
+	binary.LittleEndian.PutUint32(b[0:4], pcapMagic)
+	binary.LittleEndian.PutUint16(b[4:6], pcapVersionMajor)
+	binary.LittleEndian.PutUint16(b[6:8], pcapVersionMinor)
+	// 4 bytes: thiszone, always UTC
+	// 4 bytes: sigfigs, always zero
+	binary.LittleEndian.PutUint32(b[16:20], uint32(snaplen))
+	binary.LittleEndian.PutUint32(b[20:24], pcapLinkTypeEthernet)
+
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+
+	return &pcapWriter{
+		w:   w,
+		buf: make([]byte, pcapRecordLen),
+	}, nil
+}
+
+// writePacket writes a record for frame b, captured at t. origLen is the
+// length of the frame on the wire, which may exceed len(b) if it was
+// truncated when read.
+func (pw *pcapWriter) writePacket(t time.Time, b []byte, origLen int) error {
+	us := t.UnixNano() / int64(time.Microsecond)
+
+	binary.LittleEndian.PutUint32(pw.buf[0:4], uint32(us/1e6))
+	binary.LittleEndian.PutUint32(pw.buf[4:8], uint32(us%1e6))
+	binary.LittleEndian.PutUint32(pw.buf[8:12], uint32(len(b)))
+	binary.LittleEndian.PutUint32(pw.buf[12:16], uint32(origLen))
+
+	if _, err := pw.w.Write(pw.buf); err != nil {
+		return err
+	}
+
+	_, err := pw.w.Write(b)
+	return err
+}
@@ -0,0 +1,52 @@
+package arp
+
+import (
+	"net"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-raw"
+)
+
+// A PreparedRequest is an ARP request for a fixed target whose ethernet
+// frame has been marshaled ahead of time, so it can be sent repeatedly
+// without marshaling it again. This is useful for load generators which
+// send the same request at a high rate.
+//
+// The frame captures the Client's hardware address, IPv4 address and
+// EtherType at the time PrepareRequest was called. If any of these change,
+// the request must be prepared again.
+type PreparedRequest struct {
+	c    *Client
+	b    []byte
+	addr net.Addr
+}
+
+// PrepareRequest marshals an ARP request asking for the hardware address
+// of ip, which can then be sent many times using Send.
+func (c *Client) PrepareRequest(ip net.IP) (*PreparedRequest, error) {
+	arp, err := c.newRequest(ip)
+	if err != nil {
+		return nil, c.opError("request", err)
+	}
+
+	b, err := c.marshalFrame(arp, ethernet.BroadcastHardwareAddr)
+	if err != nil {
+		return nil, c.opError("request", err)
+	}
+
+	return &PreparedRequest{
+		c:    c,
+		b:    b,
+		addr: &net_raw.Addr{HardwareAddr: ethernet.BroadcastHardwareAddr},
+	}, nil
+}
+
+// Send writes the prepared request using its Client. Send is equivalent to
+// calling Request with the same IPv4 address, and is safe for concurrent
+// use.
+func (r *PreparedRequest) Send() error {
+	if _, err := r.c.p.WriteTo(r.b, r.addr); err != nil {
+		return r.c.opError("write", err)
+	}
+	return nil
+}
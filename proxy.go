@@ -0,0 +1,438 @@
+package arp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// A ProxyEntry is an IPv4 address which a ProxyTable answers requests for.
+type ProxyEntry struct {
+	IP net.IP
+
+	// HardwareAddr is the address sent in replies. If nil, the hardware
+	// address of the Client serving the request is used.
+	HardwareAddr net.HardwareAddr
+
+	// TTL, if non-zero, is how long after being added to a table the
+	// entry expires.
+	TTL time.Duration
+}
+
+// A proxyEntry is a ProxyEntry stored in a ProxyTable.
+type proxyEntry struct {
+	mac     net.HardwareAddr
+	expires time.Time
+}
+
+// A ProxyTable is an ARPHandler which answers requests on behalf of other
+// hosts, as a proxy ARP responder. Its entries may be changed while it is
+// being served, and replacing them is atomic: a request is answered either
+// from the old or from the new set of entries, never from a mix.
+type ProxyTable struct {
+	// entries holds a map[string]proxyEntry, which is never modified once
+	// stored. mu serializes writers.
+	entries atomic.Value
+	mu      sync.Mutex
+
+	// amu guards answers, a ring of the most recent answers in which next
+	// is the index of the oldest record once the ring is full.
+	amu     sync.Mutex
+	answers []AnswerRecord
+	next    int
+
+	// smu guards the schedule set with SetEnabled and SetActiveWindow, and
+	// the Clock set with SetClock, nil for the system clock.
+	smu        sync.Mutex
+	disabled   bool
+	start, end time.Time
+	clock      Clock
+}
+
+// maxAnswerRecords is the number of answers a ProxyTable remembers.
+const maxAnswerRecords = 1024
+
+// errInvalidWatchInterval is returned by WatchFile when its interval is
+// not positive.
+var errInvalidWatchInterval = errors.New("watch interval must be positive")
+
+// An AnswerRecord describes a request answered by a ProxyTable.
+type AnswerRecord struct {
+	// Requester is the sender hardware address of the request.
+	Requester net.HardwareAddr
+
+	// TargetIP is the address which was asked for, and HardwareAddr the
+	// address it was answered with.
+	TargetIP     net.IP
+	HardwareAddr net.HardwareAddr
+
+	// Time is the time at which the reply was sent.
+	Time time.Time
+}
+
+var _ ARPHandler = &ProxyTable{}
+
+// NewProxyTable creates a ProxyTable containing entries.
+func NewProxyTable(entries ...ProxyEntry) *ProxyTable {
+	t := &ProxyTable{}
+	t.Replace(entries)
+	return t
+}
+
+// Replace atomically replaces all entries of the table.
+func (t *ProxyTable) Replace(entries []ProxyEntry) {
+	now := t.now()
+	m := make(map[string]proxyEntry, len(entries))
+	for _, e := range entries {
+		m[e.IP.String()] = newProxyEntry(e, now)
+	}
+
+	t.mu.Lock()
+	t.entries.Store(m)
+	t.mu.Unlock()
+}
+
+// Set adds or replaces the entry for e.IP.
+func (t *ProxyTable) Set(e ProxyEntry) {
+	t.update(func(m map[string]proxyEntry) {
+		m[e.IP.String()] = newProxyEntry(e, t.now())
+	})
+}
+
+// Delete removes the entry for ip.
+func (t *ProxyTable) Delete(ip net.IP) {
+	t.update(func(m map[string]proxyEntry) {
+		delete(m, ip.String())
+	})
+}
+
+// Lookup returns the hardware address the table answers with for ip. The
+// address is nil if the entry uses the Client's hardware address.
+func (t *ProxyTable) Lookup(ip net.IP) (net.HardwareAddr, bool) {
+	return t.lookup(ip, t.now())
+}
+
+// lookup implements Lookup, expiring entries at now.
+func (t *ProxyTable) lookup(ip net.IP, now time.Time) (net.HardwareAddr, bool) {
+	e, ok := t.load()[ip.String()]
+	if !ok || (!e.expires.IsZero() && !now.Before(e.expires)) {
+		return nil, false
+	}
+
+	return e.mac, true
+}
+
+// ServeARP implements ARPHandler, replying to requests for IPv4 addresses
+// in the table. Gratuitous requests, in which a host announces its own
+// address, are not answered. Entry expiry, the active window and the
+// times of answers are measured on the Clock of c.
+func (t *ProxyTable) ServeARP(c *Client, p *net_arp.Packet, f *ethernet.Frame) {
+	if p.Operation != net_arp.OperationRequest || IPsEqual(p.SenderIP, p.TargetIP) {
+		return
+	}
+
+	now := c.cfg.clock.Now()
+	if !t.active(now) {
+		return
+	}
+
+	mac, ok := t.lookup(p.TargetIP, now)
+	if !ok {
+		return
+	}
+	if mac == nil {
+		mac = c.HardwareAddr()
+	}
+
+	if err := c.Reply(p, mac, p.TargetIP); err != nil {
+		c.cfg.logger.Printf("failed to send proxy reply to %s for %s: %v", p.SenderHardwareAddr, p.TargetIP, err)
+		return
+	}
+
+	t.record(AnswerRecord{
+		Requester:    p.SenderHardwareAddr,
+		TargetIP:     p.TargetIP,
+		HardwareAddr: mac,
+		Time:         c.cfg.clock.Now(),
+	})
+}
+
+// SetEnabled arms or disarms the table. While disabled, requests are still
+// read by the Serve loop, but none are answered, which allows a responder
+// to be switched off and on without closing its Client. Tables are enabled
+// when created. SetEnabled is safe to call while the table is served.
+func (t *ProxyTable) SetEnabled(on bool) {
+	t.smu.Lock()
+	t.disabled = !on
+	t.smu.Unlock()
+}
+
+// SetActiveWindow restricts answering requests to the time from start,
+// inclusive, to end, exclusive, as for a failover during a maintenance
+// window. Requests read outside the window are discarded. A zero start or
+// end leaves the window open on that side, so two zero times remove the
+// restriction. The window is compared with the Clock of the Client serving
+// the request, and applies in addition to SetEnabled. SetActiveWindow is
+// safe to call while the table is served.
+func (t *ProxyTable) SetActiveWindow(start, end time.Time) {
+	t.smu.Lock()
+	t.start, t.end = start, end
+	t.smu.Unlock()
+}
+
+// SetClock sets the Clock against which Set and Replace start the TTLs of
+// entries, and Lookup expires them. Since ServeARP expires entries on the
+// Clock of the Client serving the table, a table served by a Client using
+// WithClock should be given the same Clock. A nil clk restores the
+// default, the system clock. SetClock is safe to call while the table is
+// served.
+func (t *ProxyTable) SetClock(clk Clock) {
+	t.smu.Lock()
+	t.clock = clk
+	t.smu.Unlock()
+}
+
+// now returns the current time of the table's Clock.
+func (t *ProxyTable) now() time.Time {
+	t.smu.Lock()
+	clk := t.clock
+	t.smu.Unlock()
+
+	if clk == nil {
+		return time.Now()
+	}
+	return clk.Now()
+}
+
+// active reports whether the table answers requests at now.
+func (t *ProxyTable) active(now time.Time) bool {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	switch {
+	case t.disabled:
+		return false
+	case !t.start.IsZero() && now.Before(t.start):
+		return false
+	case !t.end.IsZero() && !now.Before(t.end):
+		return false
+	}
+	return true
+}
+
+// RecentAnswers returns the most recent requests the table answered, oldest
+// first, as an audit trail of what was answered for whom. Only the last
+// 1024 answers are kept. Failed replies are not recorded.
+func (t *ProxyTable) RecentAnswers() []AnswerRecord {
+	t.amu.Lock()
+	defer t.amu.Unlock()
+
+	out := make([]AnswerRecord, 0, len(t.answers))
+	out = append(out, t.answers[t.next:]...)
+	return append(out, t.answers[:t.next]...)
+}
+
+// record adds r to the ring of recent answers, replacing the oldest record
+// once the ring is full.
+func (t *ProxyTable) record(r AnswerRecord) {
+	t.amu.Lock()
+	defer t.amu.Unlock()
+
+	if len(t.answers) < maxAnswerRecords {
+		t.answers = append(t.answers, r)
+		return
+	}
+
+	t.answers[t.next] = r
+	t.next = (t.next + 1) % maxAnswerRecords
+}
+
+// LoadFile parses the proxy table file at path, see ParseProxyEntries, and
+// replaces the table's entries with its contents. If the file cannot be
+// parsed, the table is left unchanged. LoadFile can be used to reload the
+// table on demand, for example on SIGHUP.
+func (t *ProxyTable) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := ParseProxyEntries(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	t.Replace(entries)
+	return nil
+}
+
+// WatchFile loads the proxy table file at path, and then checks it every
+// interval, reloading it whenever its modification time or size change.
+// interval must be positive. Errors which occur while reloading are passed
+// to onError, if not nil, and leave the table unchanged. Call the returned
+// function to stop watching.
+//
+// WatchFile polls the file, rather than relying on platform specific file
+// system notifications, so changes are picked up within interval.
+func (t *ProxyTable) WatchFile(path string, interval time.Duration, onError func(error)) (func(), error) {
+	if interval <= 0 {
+		return nil, errInvalidWatchInterval
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.LoadFile(path); err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+			case <-stop:
+				return
+			}
+
+			nfi, err := os.Stat(path)
+			if err == nil && nfi.ModTime().Equal(fi.ModTime()) && nfi.Size() == fi.Size() {
+				continue
+			}
+			if err == nil {
+				fi = nfi
+				err = t.LoadFile(path)
+			}
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}, nil
+}
+
+// ParseProxyEntries parses proxy table entries from r. Each line contains
+// an IPv4 address, a hardware address in any format accepted by ParseMAC,
+// and an optional TTL in the format accepted by time.ParseDuration,
+// separated by whitespace. A hardware address of "-" means the Client's
+// own hardware address. Empty lines and text following a '#' are ignored.
+//
+//	# ip          mac                ttl
+//	192.0.2.10    02:00:00:00:00:0a
+//	192.0.2.11    -                  10m
+func ParseProxyEntries(r io.Reader) ([]ProxyEntry, error) {
+	var entries []ProxyEntry
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: want ip, mac and optional ttl, got %d fields", n, len(fields))
+		}
+
+		e, err := parseProxyEntry(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseProxyEntry parses the fields of a single proxy table line.
+func parseProxyEntry(fields []string) (ProxyEntry, error) {
+	if len(fields) < 2 {
+		return ProxyEntry{}, fmt.Errorf("missing hardware address for %q", fields[0])
+	}
+
+	ip := net.ParseIP(fields[0]).To4()
+	if ip == nil {
+		return ProxyEntry{}, fmt.Errorf("invalid IPv4 address %q", fields[0])
+	}
+
+	e := ProxyEntry{IP: ip}
+	if fields[1] != "-" {
+		mac, err := ParseMAC(fields[1])
+		if err != nil {
+			return ProxyEntry{}, err
+		}
+		e.HardwareAddr = mac
+	}
+
+	if len(fields) == 3 {
+		ttl, err := time.ParseDuration(fields[2])
+		if err != nil || ttl <= 0 {
+			return ProxyEntry{}, fmt.Errorf("invalid TTL %q", fields[2])
+		}
+		e.TTL = ttl
+	}
+
+	return e, nil
+}
+
+// newProxyEntry converts e into a proxyEntry added at now.
+func newProxyEntry(e ProxyEntry, now time.Time) proxyEntry {
+	pe := proxyEntry{mac: e.HardwareAddr}
+	if e.TTL > 0 {
+		pe.expires = now.Add(e.TTL)
+	}
+	return pe
+}
+
+// load returns the current entries, which are nil for a zero ProxyTable.
+func (t *ProxyTable) load() map[string]proxyEntry {
+	m, _ := t.entries.Load().(map[string]proxyEntry)
+	return m
+}
+
+// update copies the current entries, applies fn to the copy, and stores it.
+func (t *ProxyTable) update(fn func(m map[string]proxyEntry)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	old := t.load()
+	m := make(map[string]proxyEntry, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+
+	fn(m)
+	t.entries.Store(m)
+}
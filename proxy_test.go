@@ -0,0 +1,85 @@
+package arp_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	arp "github.com/pefish/go-arping"
+	"github.com/pefish/go-arping/arptest"
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+func TestProxyTableUsesClock(t *testing.T) {
+	c, conn, err := arptest.NewFakeClient()
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	clock := conn.Clock()
+	ip := net.IPv4(192, 0, 2, 10).To4()
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x0a}
+
+	table := arp.NewProxyTable()
+	table.SetClock(clock)
+	table.Set(arp.ProxyEntry{IP: ip, HardwareAddr: mac, TTL: time.Minute})
+
+	requester := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	p, err := net_arp.NewPacket(net_arp.OperationRequest, requester, net.IPv4(192, 0, 2, 2),
+		make(net.HardwareAddr, 6), ip)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	table.ServeARP(c, p, &ethernet.Frame{Source: requester})
+
+	answers := table.RecentAnswers()
+	if len(answers) != 1 || !answers[0].Time.Equal(clock.Now()) {
+		t.Fatalf("unexpected answers, want one at %v: %+v", clock.Now(), answers)
+	}
+	if n := len(conn.WrittenPackets()); n != 1 {
+		t.Fatalf("expected one reply, got %d", n)
+	}
+
+	// The entry only expires once the Clock passes its TTL.
+	clock.Advance(time.Minute - time.Nanosecond)
+	if _, ok := table.Lookup(ip); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if _, ok := table.Lookup(ip); ok {
+		t.Fatal("entry did not expire after its TTL")
+	}
+
+	table.ServeARP(c, p, &ethernet.Frame{Source: requester})
+	if n := len(table.RecentAnswers()); n != 1 {
+		t.Fatalf("expired entry was answered, %d answers", n)
+	}
+}
+
+func TestProxyTableWatchFileInvalidInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "arp-proxy")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "proxy")
+	if err := ioutil.WriteFile(path, []byte("192.0.2.10 -\n"), 0o644); err != nil {
+		t.Fatalf("failed to write proxy file: %v", err)
+	}
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		stop, err := arp.NewProxyTable().WatchFile(path, d, nil)
+		if err == nil {
+			stop()
+			t.Fatalf("expected an error for interval %v", d)
+		}
+	}
+}
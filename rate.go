@@ -0,0 +1,120 @@
+package arp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// errInvalidWindow is returned when a non-positive observation window is
+// passed to a passive analysis method.
+var errInvalidWindow = errors.New("observation window must be positive")
+
+// MeasureRate passively counts the ARP requests and replies observed on
+// the segment during window, and returns their rates per second. This
+// gives operators a baseline of normal ARP activity, for instance to choose
+// alerting thresholds. Gratuitous announcements are counted according to
+// their operation.
+//
+// Packets are classified by the time they were read, so only packets read
+// within window are counted, and window is measured on the Client's Clock.
+// Kernel receive timestamps are not used: a packet which was queued on the
+// socket before MeasureRate started, but is read after, is counted.
+// MeasureRate is built on Monitor and blocks for window, unless a read
+// fails first, in which case the error is returned.
+func (c *Client) MeasureRate(window time.Duration) (requestsPerSec, repliesPerSec float64, err error) {
+	if window <= 0 {
+		return 0, 0, errInvalidWindow
+	}
+
+	var (
+		mu         sync.Mutex
+		reqs, reps int
+	)
+
+	start := c.cfg.clock.Now()
+	end := start.Add(window)
+	sub := c.subscribe(func(ev ARPEvent) {
+		if ev.Time.Before(start) || !ev.Time.Before(end) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch ev.Packet.Operation {
+		case net_arp.OperationRequest:
+			reqs++
+		case net_arp.OperationReply:
+			reps++
+		}
+	})
+
+	select {
+	case <-c.cfg.clock.After(window):
+	case <-sub.done:
+		return 0, 0, sub.err
+	}
+	c.unsubscribe(sub)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	secs := window.Seconds()
+	return float64(reqs) / secs, float64(reps) / secs, nil
+}
+
+// DistinctHosts passively observes ARP traffic during window, and returns
+// the number of distinct source hardware addresses of the frames seen, and
+// those addresses in ascending order. This estimates the number of active
+// hosts on the segment without scanning it, for sizing, or for anomaly
+// detection against a baseline. Hosts which send no ARP traffic during
+// window are not counted.
+//
+// Like MeasureRate, DistinctHosts is built on Monitor and blocks for
+// window, unless a read fails first, in which case the error is returned.
+func (c *Client) DistinctHosts(window time.Duration) (int, []net.HardwareAddr, error) {
+	if window <= 0 {
+		return 0, nil, errInvalidWindow
+	}
+
+	var (
+		mu    sync.Mutex
+		hosts = make(map[string]net.HardwareAddr)
+	)
+
+	start := c.cfg.clock.Now()
+	end := start.Add(window)
+	sub := c.subscribe(func(ev ARPEvent) {
+		if ev.Time.Before(start) || !ev.Time.Before(end) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		mac := ev.Frame.Source
+		hosts[mac.String()] = mac
+	})
+
+	select {
+	case <-c.cfg.clock.After(window):
+	case <-sub.done:
+		return 0, nil, sub.err
+	}
+	c.unsubscribe(sub)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	macs := make([]net.HardwareAddr, 0, len(hosts))
+	for _, mac := range hosts {
+		macs = append(macs, mac)
+	}
+	sortHardwareAddrs(macs)
+
+	return len(macs), macs, nil
+}
@@ -0,0 +1,108 @@
+package arp_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	arp "github.com/pefish/go-arping"
+	"github.com/pefish/go-arping/arptest"
+)
+
+func TestMeasureRateUsesClock(t *testing.T) {
+	c, conn, err := arptest.NewFakeClient()
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	for i := 2; i < 4; i++ {
+		mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, byte(i)}
+		if err := conn.QueueReply(net.IPv4(192, 0, 2, byte(i)), mac); err != nil {
+			t.Fatalf("failed to queue reply: %v", err)
+		}
+	}
+
+	// The window is only measured on the Clock, so an hour passes as soon
+	// as the Clock is advanced.
+	const window = time.Hour
+
+	type result struct {
+		reqs, reps float64
+		err        error
+	}
+	resC := make(chan result, 1)
+	go func() {
+		reqs, reps, err := c.MeasureRate(window)
+		resC <- result{reqs, reps, err}
+	}()
+
+	// Wait for the queued replies to be read, then advance the Clock until
+	// MeasureRate has armed its timer and returns.
+	conn.WaitRead()
+	timeout := time.After(5 * time.Second)
+	for {
+		conn.Clock().Advance(window)
+
+		select {
+		case res := <-resC:
+			if res.err != nil {
+				t.Fatalf("failed to measure rate: %v", res.err)
+			}
+			if want := 2 / window.Seconds(); res.reqs != 0 || res.reps != want {
+				t.Fatalf("unexpected rates: %v requests/s, %v replies/s, want 0 and %v",
+					res.reqs, res.reps, want)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			t.Fatal("MeasureRate did not return after advancing the Clock")
+		}
+	}
+}
+
+func TestDistinctHostsUsesClock(t *testing.T) {
+	c, conn, err := arptest.NewFakeClient()
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	for i := 0; i < 2; i++ {
+		if err := conn.QueueReply(net.IPv4(192, 0, 2, 2), mac); err != nil {
+			t.Fatalf("failed to queue reply: %v", err)
+		}
+	}
+
+	type result struct {
+		n    int
+		macs []net.HardwareAddr
+		err  error
+	}
+	resC := make(chan result, 1)
+	go func() {
+		n, macs, err := c.DistinctHosts(arp.DefaultTimeout)
+		resC <- result{n, macs, err}
+	}()
+
+	conn.WaitRead()
+	timeout := time.After(5 * time.Second)
+	for {
+		conn.Clock().Advance(arp.DefaultTimeout)
+
+		select {
+		case res := <-resC:
+			if res.err != nil {
+				t.Fatalf("failed to count hosts: %v", res.err)
+			}
+			if res.n != 1 || len(res.macs) != 1 || res.macs[0].String() != mac.String() {
+				t.Fatalf("unexpected hosts: %d, %v", res.n, res.macs)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			t.Fatal("DistinctHosts did not return after advancing the Clock")
+		}
+	}
+}
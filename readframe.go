@@ -0,0 +1,155 @@
+package arp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// errShortBuffer is returned by ReadFrame when its buffer cannot hold an
+// ethernet header.
+var errShortBuffer = errors.New("buffer is too short for an ethernet frame")
+
+// frameScratch holds the values ReadFrame decodes into, so it does not
+// allocate them for every frame.
+type frameScratch struct {
+	p           net_arp.Packet
+	vlan, svlan ethernet.VLAN
+}
+
+// ReadFrame reads a single ARP packet like Read, but decodes its ethernet
+// frame into f and reads it into buf, so no frame or buffer is allocated.
+// This is meant for the tightest monitoring loops. buf should be at least
+// 128 bytes long, since longer frames are truncated to its length.
+//
+// The results alias memory which is reused by the next call: the address
+// fields and payload of f, and the address fields of the returned Packet,
+// are slices of buf, and the returned Packet and the VLAN tags of f are
+// owned by the Client and overwritten by the next call to ReadFrame.
+// Callers must copy any value they retain across calls, and must not call
+// ReadFrame concurrently.
+//
+// Apart from the values above, ReadFrame itself does not allocate. The
+// net.PacketConn may: the socket created by Dial allocates the source
+// address of every frame it reads. Errors are returned as an *OpError.
+func (c *Client) ReadFrame(f *ethernet.Frame, buf []byte) (*net_arp.Packet, error) {
+	if len(buf) < headerLen {
+		return nil, c.opError("read", errShortBuffer)
+	}
+
+	if err := c.checkLink(nil); err != nil {
+		return nil, c.opError("read", err)
+	}
+
+	s := &c.scratch
+	for {
+		n, _, err := c.p.ReadFrom(buf)
+		if err != nil {
+			return nil, c.opError("read", c.checkLink(err))
+		}
+		b := truncate(buf, n)
+		c.tapFrame(c.cfg.clock.Now(), b, n)
+
+		if err := decodeFrame(f, b, s); err != nil {
+			return nil, c.opError("read", &frameError{err: err})
+		}
+
+		// Ignore frames from other VLANs, and frames which do not carry
+		// the configured ARP EtherType
+		if !c.cfg.matchVLAN(f) || f.EtherType != c.cfg.etherType {
+			continue
+		}
+
+		if err := decodePacket(&s.p, f.Payload); err != nil {
+			return nil, c.opError("read", &frameError{err: err})
+		}
+		if c.cfg.strict {
+			if err := checkTrailing(&s.p, f.Payload); err != nil {
+				return nil, c.opError("read", &frameError{err: err})
+			}
+		}
+		return &s.p, nil
+	}
+}
+
+// decodeFrame decodes the ethernet frame b into f like
+// ethernet.Frame.UnmarshalBinary, but without copying: the addresses and
+// payload of f alias b, and its VLAN tags are stored in s.
+func decodeFrame(f *ethernet.Frame, b []byte, s *frameScratch) error {
+	if len(b) < headerLen {
+		return io.ErrUnexpectedEOF
+	}
+
+	f.Destination = net.HardwareAddr(b[0:6])
+	f.Source = net.HardwareAddr(b[6:12])
+	f.ServiceVLAN, f.VLAN = nil, nil
+
+	n := headerLen
+	et := ethernet.EtherType(binary.BigEndian.Uint16(b[n-2 : n]))
+	if et == ethernet.EtherTypeServiceVLAN {
+		if len(b) < n+vlanTagLen {
+			return io.ErrUnexpectedEOF
+		}
+		if err := s.svlan.UnmarshalBinary(b[n : n+2]); err != nil {
+			return err
+		}
+		f.ServiceVLAN = &s.svlan
+
+		// A service tag must be followed by a customer tag.
+		et = ethernet.EtherType(binary.BigEndian.Uint16(b[n+2 : n+4]))
+		if et != ethernet.EtherTypeVLAN {
+			return ethernet.ErrInvalidVLAN
+		}
+		n += vlanTagLen
+	}
+	if et == ethernet.EtherTypeVLAN {
+		if len(b) < n+vlanTagLen {
+			return io.ErrUnexpectedEOF
+		}
+		if err := s.vlan.UnmarshalBinary(b[n : n+2]); err != nil {
+			return err
+		}
+		f.VLAN = &s.vlan
+
+		et = ethernet.EtherType(binary.BigEndian.Uint16(b[n+2 : n+4]))
+		n += vlanTagLen
+	}
+
+	f.EtherType = et
+	f.Payload = b[n:]
+	return nil
+}
+
+// decodePacket decodes the ARP packet b into p like
+// net_arp.Packet.UnmarshalBinary, but the addresses of p alias b.
+func decodePacket(p *net_arp.Packet, b []byte) error {
+	if len(b) < 8 {
+		return io.ErrUnexpectedEOF
+	}
+
+	p.HardwareType = binary.BigEndian.Uint16(b[0:2])
+	p.ProtocolType = binary.BigEndian.Uint16(b[2:4])
+	p.HardwareAddrLength = b[4]
+	p.IPLength = b[5]
+	p.Operation = net_arp.Operation(binary.BigEndian.Uint16(b[6:8]))
+
+	ml, il := int(p.HardwareAddrLength), int(p.IPLength)
+	if len(b) < 8+2*ml+2*il {
+		return io.ErrUnexpectedEOF
+	}
+
+	n := 8
+	p.SenderHardwareAddr = net.HardwareAddr(b[n : n+ml : n+ml])
+	n += ml
+	p.SenderIP = net.IP(b[n : n+il : n+il])
+	n += il
+	p.TargetHardwareAddr = net.HardwareAddr(b[n : n+ml : n+ml])
+	n += ml
+	p.TargetIP = net.IP(b[n : n+il : n+il])
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package arp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// repeatConn is a net.PacketConn which reads the same frame forever,
+// without allocating.
+type repeatConn struct {
+	frame []byte
+}
+
+func (c *repeatConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	return copy(b, c.frame), nil, nil
+}
+
+func (c *repeatConn) WriteTo(b []byte, _ net.Addr) (int, error) { return len(b), nil }
+func (c *repeatConn) Close() error                              { return nil }
+func (c *repeatConn) LocalAddr() net.Addr                       { return nil }
+func (c *repeatConn) SetDeadline(time.Time) error               { return nil }
+func (c *repeatConn) SetReadDeadline(time.Time) error           { return nil }
+func (c *repeatConn) SetWriteDeadline(time.Time) error          { return nil }
+
+// repeatClient creates a Client reading frame from a repeatConn.
+func repeatClient(tb testing.TB, frame []byte) *Client {
+	tb.Helper()
+
+	ifi := &net.Interface{
+		Index:        1,
+		Name:         "test0",
+		MTU:          1500,
+		HardwareAddr: testMAC,
+		Flags:        net.FlagUp | net.FlagBroadcast,
+	}
+	addrs := []net.Addr{&net.IPNet{
+		IP:   testIP,
+		Mask: net.CIDRMask(24, 32),
+	}}
+
+	cfg, err := newConfig([]Option{WithLogger(log.New(ioutil.Discard, "", 0))})
+	if err != nil {
+		tb.Fatalf("failed to apply options: %v", err)
+	}
+
+	c, err := newClient(ifi, &repeatConn{frame: frame}, addrs, cfg)
+	if err != nil {
+		tb.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+// taggedARPFrame returns an 802.1Q tagged ARP request from peerIP for
+// testIP.
+func taggedARPFrame(tb testing.TB) []byte {
+	tb.Helper()
+
+	p, err := net_arp.NewPacket(net_arp.OperationRequest, peerMAC, peerIP, make(net.HardwareAddr, 6), testIP)
+	if err != nil {
+		tb.Fatalf("failed to create packet: %v", err)
+	}
+	pb, err := p.MarshalBinary()
+	if err != nil {
+		tb.Fatalf("failed to marshal packet: %v", err)
+	}
+
+	f := &ethernet.Frame{
+		Destination: ethernet.BroadcastHardwareAddr,
+		Source:      peerMAC,
+		VLAN:        &ethernet.VLAN{ID: 10},
+		EtherType:   ethernet.EtherTypeARP,
+		Payload:     pb,
+	}
+	fb, err := f.MarshalBinary()
+	if err != nil {
+		tb.Fatalf("failed to marshal frame: %v", err)
+	}
+	return fb
+}
+
+func TestReadFrameDoesNotAllocate(t *testing.T) {
+	c := repeatClient(t, taggedARPFrame(t))
+
+	var f ethernet.Frame
+	buf := make([]byte, 128)
+
+	p, err := c.ReadFrame(&f, buf)
+	if err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	if p.Operation != net_arp.OperationRequest || !bytes.Equal(p.SenderIP, peerIP) ||
+		f.VLAN == nil || f.VLAN.ID != 10 {
+		t.Fatalf("unexpected packet %+v in frame %+v", p, f)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := c.ReadFrame(&f, buf); err != nil {
+			t.Fatalf("failed to read frame: %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("ReadFrame allocated %v times per call, want 0", allocs)
+	}
+}
+
+func BenchmarkReadFrame(b *testing.B) {
+	c := repeatClient(b, taggedARPFrame(b))
+
+	var f ethernet.Frame
+	buf := make([]byte, 128)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := c.ReadFrame(&f, buf); err != nil {
+			b.Fatalf("failed to read frame: %v", err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package arp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pefish/go-net-raw"
+)
+
+var (
+	// errNotPcap is returned by ReplayPcap when its input does not start
+	// with a libpcap magic number.
+	errNotPcap = errors.New("not a libpcap file")
+
+	// errReplayClosed is returned by a replay net.PacketConn after Close.
+	errReplayClosed = errors.New("replay connection closed")
+)
+
+// maxPcapRecordLen bounds the length of a replayed frame when the capture
+// does not declare a smaller snapshot length, so a corrupt record length
+// cannot force a huge allocation.
+const maxPcapRecordLen = 65535
+
+// A timeoutError is returned by in-memory net.PacketConns when a deadline
+// expires.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// ReplayPcap reads a libpcap format capture from r, and returns a
+// net.PacketConn whose ReadFrom yields the recorded frames in order, as
+// fast as they are read, and then io.EOF. Reads fail with a timeout once
+// the read deadline has passed. The returned conn can be passed
+// to New to feed captured traffic through Read, Resolve and Monitor, in
+// tests or to reproduce an issue. Written frames are discarded.
+//
+// Both microsecond and nanosecond resolution files are supported, in
+// either byte order. The capture must use the Ethernet link type.
+func ReplayPcap(r io.Reader) (net.PacketConn, error) {
+	return newReplayConn(r, false)
+}
+
+// ReplayPcapTimed is like ReplayPcap, but honors the recorded time between
+// frames, so each frame is returned by ReadFrom no earlier than its offset
+// from the first frame. Read deadlines interrupt the wait.
+func ReplayPcapTimed(r io.Reader) (net.PacketConn, error) {
+	return newReplayConn(r, true)
+}
+
+// A replayConn is a net.PacketConn which reads frames from a pcap file.
+type replayConn struct {
+	r     io.Reader
+	order binary.ByteOrder
+	nanos bool
+	timed bool
+
+	// maxLen is the largest record length accepted, from the snapshot
+	// length of the capture.
+	maxLen uint32
+
+	// rmu serializes reads.
+	rmu sync.Mutex
+	// base is the wall clock time corresponding to the timestamp zero,
+	// set by the first read in timed mode.
+	base time.Time
+	hdr  [pcapRecordLen]byte
+
+	// frame and ts are the frame read from the capture which has not
+	// been returned yet, because its wait was interrupted.
+	frame []byte
+	ts    time.Duration
+
+	mu       sync.Mutex
+	deadline time.Time
+	// wake is closed and replaced when the deadline changes.
+	wake   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+var _ net.PacketConn = &replayConn{}
+
+// newReplayConn validates the pcap global header read from r.
+func newReplayConn(r io.Reader, timed bool) (*replayConn, error) {
+	b := make([]byte, pcapHeaderLen)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	c := &replayConn{
+		r:      r,
+		timed:  timed,
+		wake:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		if m := order.Uint32(b[0:4]); m == pcapMagic || m == pcapMagicNanos {
+			c.order = order
+			c.nanos = m == pcapMagicNanos
+			break
+		}
+	}
+	if c.order == nil {
+		return nil, errNotPcap
+	}
+
+	if lt := c.order.Uint32(b[20:24]); lt != pcapLinkTypeEthernet {
+		return nil, fmt.Errorf("unsupported pcap link type %d, want Ethernet (%d)", lt, pcapLinkTypeEthernet)
+	}
+
+	c.maxLen = c.order.Uint32(b[16:20])
+	if c.maxLen == 0 || c.maxLen > maxPcapRecordLen {
+		c.maxLen = maxPcapRecordLen
+	}
+
+	return c, nil
+}
+
+// ReadFrom implements the net.PacketConn ReadFrom method. Frames larger
+// than b are truncated. A frame whose wait is interrupted by the read
+// deadline is kept, and returned by the next ReadFrom.
+func (c *replayConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	select {
+	case <-c.closed:
+		return 0, nil, errReplayClosed
+	default:
+	}
+
+	if c.frame == nil {
+		if err := c.next(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if c.timed && c.base.IsZero() {
+		c.base = time.Now().Add(-c.ts)
+	}
+
+	// In timed mode, wait for the recorded time of the frame. Otherwise,
+	// only check for an expired deadline.
+	var at time.Time
+	if c.timed {
+		at = c.base.Add(c.ts)
+	}
+	if err := c.wait(at); err != nil {
+		return 0, nil, err
+	}
+
+	frame := c.frame
+	c.frame = nil
+
+	addr := &net_raw.Addr{}
+	if len(frame) >= 12 {
+		addr.HardwareAddr = net.HardwareAddr(append([]byte(nil), frame[6:12]...))
+	}
+
+	return copy(b, frame), addr, nil
+}
+
+// next reads the next record of the capture into c.frame and c.ts.
+func (c *replayConn) next() error {
+	if _, err := io.ReadFull(c.r, c.hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return err
+		}
+		return io.EOF
+	}
+
+	frac := time.Duration(c.order.Uint32(c.hdr[4:8]))
+	if !c.nanos {
+		frac *= time.Microsecond
+	}
+	ts := time.Duration(c.order.Uint32(c.hdr[0:4]))*time.Second + frac
+
+	n := c.order.Uint32(c.hdr[8:12])
+	if n > c.maxLen {
+		return fmt.Errorf("pcap record of %d bytes exceeds the snapshot length of %d bytes", n, c.maxLen)
+	}
+
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(c.r, frame); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	c.frame, c.ts = frame, ts
+	return nil
+}
+
+// wait blocks until t, the read deadline, or Close.
+func (c *replayConn) wait(t time.Time) error {
+	for {
+		c.mu.Lock()
+		deadline, wake := c.deadline, c.wake
+		c.mu.Unlock()
+
+		now := time.Now()
+		if !deadline.IsZero() && !now.Before(deadline) {
+			return timeoutError{}
+		}
+		if !now.Before(t) {
+			return nil
+		}
+
+		until := t
+		if !deadline.IsZero() && deadline.Before(until) {
+			until = deadline
+		}
+
+		timer := time.NewTimer(until.Sub(now))
+		select {
+		case <-timer.C:
+		case <-wake:
+		case <-c.closed:
+			timer.Stop()
+			return errReplayClosed
+		}
+		timer.Stop()
+	}
+}
+
+// WriteTo implements the net.PacketConn WriteTo method, discarding b.
+func (c *replayConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, errReplayClosed
+	default:
+		return len(b), nil
+	}
+}
+
+// Close implements the net.PacketConn Close method. It does not close the
+// underlying reader.
+func (c *replayConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// LocalAddr implements the net.PacketConn LocalAddr method.
+func (c *replayConn) LocalAddr() net.Addr {
+	return &net_raw.Addr{}
+}
+
+// SetDeadline implements the net.PacketConn SetDeadline method.
+func (c *replayConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements the net.PacketConn SetReadDeadline method.
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	close(c.wake)
+	c.wake = make(chan struct{})
+	c.mu.Unlock()
+	return nil
+}
+
+// SetWriteDeadline implements the net.PacketConn SetWriteDeadline method.
+// Writes never block, so it has no effect.
+func (c *replayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
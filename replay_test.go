@@ -0,0 +1,69 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReplayPcapRecordTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := newPcapWriter(&buf, 64)
+	if err != nil {
+		t.Fatalf("failed to write pcap header: %v", err)
+	}
+	if err := pw.writePacket(time.Now(), make([]byte, 128), 128); err != nil {
+		t.Fatalf("failed to write pcap record: %v", err)
+	}
+
+	p, err := ReplayPcap(&buf)
+	if err != nil {
+		t.Fatalf("failed to open replay: %v", err)
+	}
+	defer p.Close()
+
+	if _, _, err := p.ReadFrom(make([]byte, 1500)); err == nil {
+		t.Fatal("expected an error for a record exceeding the snapshot length")
+	}
+}
+
+func TestReplayPcapKeepsFrameAfterDeadline(t *testing.T) {
+	want := ethernetFrame(t, peerMAC, testMAC, []byte("hello"))
+
+	var buf bytes.Buffer
+	pw, err := newPcapWriter(&buf, 65535)
+	if err != nil {
+		t.Fatalf("failed to write pcap header: %v", err)
+	}
+	if err := pw.writePacket(time.Now(), want, len(want)); err != nil {
+		t.Fatalf("failed to write pcap record: %v", err)
+	}
+
+	p, err := ReplayPcap(&buf)
+	if err != nil {
+		t.Fatalf("failed to open replay: %v", err)
+	}
+	defer p.Close()
+
+	b := make([]byte, 1500)
+
+	if err := p.SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	_, _, err = p.ReadFrom(b)
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout, got: %v", err)
+	}
+
+	if err := p.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("failed to clear read deadline: %v", err)
+	}
+	n, _, err := p.ReadFrom(b)
+	if err != nil {
+		t.Fatalf("failed to read frame after timeout: %v", err)
+	}
+	if got := b[:n]; !bytes.Equal(want, got) {
+		t.Fatalf("unexpected frame:\n- want: %x\n-  got: %x", want, got)
+	}
+}
@@ -0,0 +1,212 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// Default parameters of the detectors which Summarize runs over a session.
+const (
+	reportWindow         = time.Minute
+	reportScanThreshold  = 64
+	reportClaimThreshold = 16
+
+	// maxTopTalkers is the number of hosts listed in Report.TopTalkers.
+	maxTopTalkers = 10
+)
+
+// A ReportConfig sets the parameters of the detectors which Summarize runs
+// over a session. Zero fields select the defaults.
+type ReportConfig struct {
+	// Window is the window of the detectors, one minute by default.
+	Window time.Duration
+
+	// ScanThreshold is the threshold of DetectScanning, 64 targets by
+	// default, and ClaimThreshold the threshold of DetectMACClaimCount,
+	// 16 addresses by default.
+	ScanThreshold  int
+	ClaimThreshold int
+}
+
+// A Report summarizes the ARP traffic of a monitoring session, see
+// Client.Summarize.
+type Report struct {
+	// Start and End are the times of the first and the last event, and
+	// Requests and Replies count the events of each operation.
+	Start, End        time.Time
+	Requests, Replies int
+
+	// Hosts are the distinct sender hardware addresses, in ascending
+	// order.
+	Hosts []net.HardwareAddr
+
+	// Bindings are the IPv4 to hardware address bindings observed from
+	// senders, sorted by IPv4 address. For an address claimed by several
+	// hosts, the binding is the most recent claim, and the address is
+	// also listed in Conflicts.
+	Bindings  []Binding
+	Conflicts []AddrConflict
+
+	// TopTalkers are the hosts which sent the most requests, at most 10,
+	// in descending order of their request count.
+	TopTalkers []Talker
+
+	// Anomalies reported by DetectScanning, DetectMACClaimCount and
+	// DetectUnsolicitedReplies, had they monitored the session.
+	Scans       []ScanEvent
+	Claims      []ClaimEvent
+	Unsolicited []ARPEvent
+}
+
+// An AddrConflict is an IPv4 address claimed by more than one hardware
+// address.
+type AddrConflict struct {
+	IP net.IP
+
+	// HardwareAddrs are the claiming addresses, in ascending order.
+	HardwareAddrs []net.HardwareAddr
+}
+
+// A Talker is a host and the number of ARP requests it sent.
+type Talker struct {
+	HardwareAddr net.HardwareAddr
+	Requests     int
+}
+
+// Summarize aggregates events, such as those collected from MonitorFor
+// during a time-boxed session, into a Report of the hosts, bindings,
+// conflicts and top talkers observed, and of the anomalies detected in
+// the traffic. Events are processed in the order of their Time, and events
+// without a Packet are ignored.
+//
+// Anomalies are detected as by the detectors of the Client, with the
+// parameters set in cfg. A nil cfg selects the defaults. The hardware
+// addresses passed to WithTrustedClaimers are excluded from the claim
+// count. Summarize only inspects events, so it may be used on events
+// recorded by any Client.
+func (c *Client) Summarize(events []ARPEvent, cfg *ReportConfig) Report {
+	window, scanThreshold, claimThreshold := reportWindow, reportScanThreshold, reportClaimThreshold
+	if cfg != nil {
+		if cfg.Window != 0 {
+			window = cfg.Window
+		}
+		if cfg.ScanThreshold != 0 {
+			scanThreshold = cfg.ScanThreshold
+		}
+		if cfg.ClaimThreshold != 0 {
+			claimThreshold = cfg.ClaimThreshold
+		}
+	}
+
+	evs := make([]ARPEvent, 0, len(events))
+	for _, ev := range events {
+		if ev.Packet != nil {
+			evs = append(evs, ev)
+		}
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		return evs[i].Time.Before(evs[j].Time)
+	})
+
+	var r Report
+	if len(evs) == 0 {
+		return r
+	}
+	r.Start, r.End = evs[0].Time, evs[len(evs)-1].Time
+
+	scans := newScanDetector(window, scanThreshold)
+	claims := newClaimDetector(window, claimThreshold, c.cfg.trustedClaimers)
+	replies := newReplyDetector(window)
+
+	hosts := make(map[string]net.HardwareAddr)
+	requests := make(map[string]int)
+	bindings := make(map[string]Binding)
+	claimers := make(map[string]map[string]net.HardwareAddr)
+
+	for _, ev := range evs {
+		p := ev.Packet
+		src := p.SenderHardwareAddr.String()
+		hosts[src] = p.SenderHardwareAddr
+
+		switch p.Operation {
+		case net_arp.OperationRequest:
+			r.Requests++
+			requests[src]++
+		case net_arp.OperationReply:
+			r.Replies++
+		}
+
+		// Probes, sent from the unspecified address, bind nothing.
+		if ip := p.SenderIP.To4(); ip != nil && !ip.Equal(net.IPv4zero) {
+			k := ip.String()
+			bindings[k] = Binding{IP: ip, HardwareAddr: p.SenderHardwareAddr, Seen: ev.Time}
+
+			if claimers[k] == nil {
+				claimers[k] = make(map[string]net.HardwareAddr)
+			}
+			claimers[k][src] = p.SenderHardwareAddr
+		}
+
+		if sev, ok := scans.observe(ev); ok {
+			r.Scans = append(r.Scans, sev)
+		}
+		if cev, ok := claims.observe(ev); ok {
+			r.Claims = append(r.Claims, cev)
+		}
+		if replies.unsolicited(ev) {
+			r.Unsolicited = append(r.Unsolicited, ev)
+		}
+	}
+
+	for _, mac := range hosts {
+		r.Hosts = append(r.Hosts, mac)
+	}
+	sortHardwareAddrs(r.Hosts)
+
+	for k, b := range bindings {
+		r.Bindings = append(r.Bindings, b)
+
+		if len(claimers[k]) < 2 {
+			continue
+		}
+		conflict := AddrConflict{IP: b.IP}
+		for _, mac := range claimers[k] {
+			conflict.HardwareAddrs = append(conflict.HardwareAddrs, mac)
+		}
+		sortHardwareAddrs(conflict.HardwareAddrs)
+		r.Conflicts = append(r.Conflicts, conflict)
+	}
+	sort.Slice(r.Bindings, func(i, j int) bool {
+		return bytes.Compare(r.Bindings[i].IP, r.Bindings[j].IP) < 0
+	})
+	sort.Slice(r.Conflicts, func(i, j int) bool {
+		return bytes.Compare(r.Conflicts[i].IP, r.Conflicts[j].IP) < 0
+	})
+
+	for k, n := range requests {
+		r.TopTalkers = append(r.TopTalkers, Talker{HardwareAddr: hosts[k], Requests: n})
+	}
+	sort.Slice(r.TopTalkers, func(i, j int) bool {
+		a, b := r.TopTalkers[i], r.TopTalkers[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return bytes.Compare(a.HardwareAddr, b.HardwareAddr) < 0
+	})
+	if len(r.TopTalkers) > maxTopTalkers {
+		r.TopTalkers = r.TopTalkers[:maxTopTalkers]
+	}
+
+	return r
+}
+
+// sortHardwareAddrs sorts macs in ascending order.
+func sortHardwareAddrs(macs []net.HardwareAddr) {
+	sort.Slice(macs, func(i, j int) bool {
+		return bytes.Compare(macs[i], macs[j]) < 0
+	})
+}
@@ -0,0 +1,130 @@
+package arp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+func TestSummarize(t *testing.T) {
+	var (
+		mac3 = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x03}
+		mac4 = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x04}
+	)
+
+	events := []ARPEvent{
+		// The events are sorted by time, so this one comes last.
+		arpEvent(t, net_arp.OperationRequest, testMAC, testIP, hostIP(11), 6*time.Second),
+
+		// testIP resolves peerIP, which answers.
+		arpEvent(t, net_arp.OperationRequest, testMAC, testIP, peerIP, 0),
+		arpEvent(t, net_arp.OperationReply, peerMAC, peerIP, testIP, time.Second),
+
+		// mac3 answers for two addresses nobody asked for.
+		arpEvent(t, net_arp.OperationReply, mac3, hostIP(3), testIP, 2*time.Second),
+		arpEvent(t, net_arp.OperationReply, mac3, hostIP(4), testIP, 3*time.Second),
+
+		// mac4 announces peerIP, which conflicts with peerMAC.
+		arpEvent(t, net_arp.OperationRequest, mac4, peerIP, peerIP, 4*time.Second),
+
+		// testIP requests an address which does not answer.
+		arpEvent(t, net_arp.OperationRequest, testMAC, testIP, hostIP(10), 5*time.Second),
+
+		// Events without a packet are ignored.
+		{Time: detectStart.Add(time.Hour)},
+	}
+
+	want := Report{
+		Start:    detectStart,
+		End:      detectStart.Add(6 * time.Second),
+		Requests: 4,
+		Replies:  3,
+		Hosts:    []net.HardwareAddr{testMAC, peerMAC, mac3, mac4},
+		Bindings: []Binding{
+			{IP: testIP, HardwareAddr: testMAC, Seen: detectStart.Add(6 * time.Second)},
+			{IP: peerIP, HardwareAddr: mac4, Seen: detectStart.Add(4 * time.Second)},
+			{IP: hostIP(3), HardwareAddr: mac3, Seen: detectStart.Add(2 * time.Second)},
+			{IP: hostIP(4), HardwareAddr: mac3, Seen: detectStart.Add(3 * time.Second)},
+		},
+		Conflicts: []AddrConflict{
+			{IP: peerIP, HardwareAddrs: []net.HardwareAddr{peerMAC, mac4}},
+		},
+		TopTalkers: []Talker{
+			{HardwareAddr: testMAC, Requests: 3},
+			{HardwareAddr: mac4, Requests: 1},
+		},
+		Unsolicited: []ARPEvent{events[3], events[4]},
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		c, _ := testClient(t)
+		defer c.Close()
+
+		got := c.Summarize(events, nil)
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("unexpected report:\n- want: %+v\n-  got: %+v", want, got)
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		c, _ := testClient(t)
+		defer c.Close()
+
+		// With low thresholds, testIP scans: peerIP answered and counts
+		// half, so the third target crosses a threshold of 2. mac3 claims
+		// more than 1 address.
+		want := want
+		want.Scans = []ScanEvent{{
+			HardwareAddr: testMAC,
+			Targets:      []net.IP{peerIP, hostIP(10), hostIP(11)},
+			Unanswered:   2,
+			Time:         detectStart.Add(6 * time.Second),
+		}}
+		want.Claims = []ClaimEvent{{
+			HardwareAddr: mac3,
+			IPs:          []net.IP{hostIP(3), hostIP(4)},
+			Time:         detectStart.Add(3 * time.Second),
+		}}
+
+		got := c.Summarize(events, &ReportConfig{
+			Window:         time.Minute,
+			ScanThreshold:  2,
+			ClaimThreshold: 1,
+		})
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("unexpected report:\n- want: %+v\n-  got: %+v", want, got)
+		}
+
+		// A window too short to span the session detects nothing.
+		got = c.Summarize(events, &ReportConfig{
+			Window:         time.Second,
+			ScanThreshold:  2,
+			ClaimThreshold: 1,
+		})
+		if len(got.Scans) != 0 || len(got.Claims) != 0 {
+			t.Fatalf("unexpected anomalies with a short window: %+v, %+v", got.Scans, got.Claims)
+		}
+	})
+
+	t.Run("trusted claimers", func(t *testing.T) {
+		c, _ := testClient(t, WithTrustedClaimers(mac3))
+		defer c.Close()
+
+		got := c.Summarize(events, &ReportConfig{ClaimThreshold: 1})
+		if len(got.Claims) != 0 {
+			t.Fatalf("trusted claimer reported: %+v", got.Claims)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		c, _ := testClient(t)
+		defer c.Close()
+
+		if got := c.Summarize(nil, nil); !reflect.DeepEqual(Report{}, got) {
+			t.Fatalf("unexpected report for no events: %+v", got)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package arp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+var (
+	// ErrMultipleReplies is matched by the error returned from Resolve when
+	// the DuplicateError policy is in effect and more than one hardware
+	// address answered for the same IPv4 address. Use errors.As with a
+	// *MultipleRepliesError to retrieve the addresses.
+	ErrMultipleReplies = errors.New("multiple hardware addresses replied for IPv4 address")
+
+	// errInvalidDuplicatePolicy is returned when an unknown DuplicatePolicy
+	// is passed to WithDuplicatePolicy.
+	errInvalidDuplicatePolicy = errors.New("invalid duplicate reply policy")
+)
+
+// A DuplicatePolicy determines how Resolve handles more than one host
+// answering the same request, which usually indicates an address conflict
+// or a spoofing attempt.
+type DuplicatePolicy int
+
+// DuplicatePolicy constants which can be passed to WithDuplicatePolicy.
+const (
+	// DuplicateFirst returns the first hardware address which replies and
+	// ignores any other answers. This is the default.
+	DuplicateFirst DuplicatePolicy = iota
+
+	// DuplicateError waits for the duplicate grace window after the first
+	// reply, and returns a *MultipleRepliesError if more than one hardware
+	// address answered.
+	DuplicateError
+)
+
+// A MultipleRepliesError is returned by Resolve when the DuplicateError
+// policy is in effect and several hardware addresses answered for IP.
+type MultipleRepliesError struct {
+	IP            net.IP
+	HardwareAddrs []net.HardwareAddr
+}
+
+// Error implements error.
+func (e *MultipleRepliesError) Error() string {
+	return fmt.Sprintf("%d hardware addresses replied for %s: %v", len(e.HardwareAddrs), e.IP, e.HardwareAddrs)
+}
+
+// Is reports whether target is ErrMultipleReplies.
+func (e *MultipleRepliesError) Is(target error) bool {
+	return target == ErrMultipleReplies
+}
+
+// Resolve performs an ARP request, attempting to retrieve the hardware
+// address of a machine using its IPv4 address. Resolve must not be used
+// concurrently with Read. If you're using Read (usually in a loop), you
+// need to use Request instead. Resolve may read more than one message if
+// it receives messages unrelated to the request.
+//
+// How additional replies are handled depends on the DuplicatePolicy set
+// with WithDuplicatePolicy.
+//
+// If no read deadline is set on the Client, Resolve gives up after the
+// default timeout set with WithDefaultTimeout. A deadline set with
+// SetDeadline or SetReadDeadline always takes precedence.
+func (c *Client) Resolve(ip net.IP) (net.HardwareAddr, error) {
+	return c.ResolveContext(context.Background(), ip)
+}
+
+// ResolveContext is like Resolve, but gives up when ctx is done, in which
+// case ctx.Err() is returned. If ctx has a deadline, it takes precedence
+// over the default timeout, and the earlier of it and any read deadline set
+// on the Client applies.
+func (c *Client) ResolveContext(ctx context.Context, ip net.IP) (net.HardwareAddr, error) {
+	macs, err := c.resolve(ctx, ip, c.cfg.duplicates != DuplicateFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(macs) > 1 && c.cfg.duplicates == DuplicateError {
+		return nil, &MultipleRepliesError{
+			IP:            normalizeIP(ip),
+			HardwareAddrs: macs,
+		}
+	}
+
+	return macs[0], nil
+}
+
+// ResolveTimeout is like Resolve, but gives up after timeout, or earlier if
+// a read deadline set on the Client is reached first, and then fails with a
+// timeout error just like Resolve does. The Client's read deadline is
+// restored once ResolveTimeout returns, so it does not affect later calls
+// to Read.
+func (c *Client) ResolveTimeout(ip net.IP, timeout time.Duration) (net.HardwareAddr, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mac, err := c.ResolveContext(ctx, ip)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, c.opError("read", timeoutError{})
+	}
+	return mac, err
+}
+
+// ResolveAll performs an ARP request like Resolve, but returns every
+// distinct hardware address which answered within the duplicate grace
+// window following the first reply, in the order they were received.
+// ResolveAll is unaffected by the DuplicatePolicy.
+func (c *Client) ResolveAll(ip net.IP) ([]net.HardwareAddr, error) {
+	return c.resolve(context.Background(), ip, true)
+}
+
+// resolve sends a request for ip and waits for the first reply. If collect
+// is true, it keeps reading for the duplicate grace window and returns all
+// distinct hardware addresses seen.
+func (c *Client) resolve(ctx context.Context, ip net.IP, collect bool) ([]net.HardwareAddr, error) {
+	deadline, restore, err := c.bound(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	if err := c.Request(ip); err != nil {
+		return nil, err
+	}
+
+	mac, err := c.awaitReply(ip)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	macs := []net.HardwareAddr{mac}
+	if !collect || c.cfg.duplicateGrace == 0 {
+		return macs, nil
+	}
+
+	// Shorten the read deadline to the end of the grace window, without
+	// extending the deadline of the operation. bound restores the
+	// caller's deadline once done.
+	grace := c.cfg.clock.Now().Add(c.cfg.duplicateGrace)
+	if !deadline.IsZero() && deadline.Before(grace) {
+		grace = deadline
+	}
+
+	if err := c.p.SetReadDeadline(grace); err != nil {
+		return nil, c.opError("set deadline", err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	for {
+		mac, err := c.awaitReply(ip)
+		if err != nil {
+			if isTimeout(err) || ctx.Err() != nil {
+				return macs, nil
+			}
+			return nil, err
+		}
+
+		if !containsHardwareAddr(macs, mac) {
+			macs = append(macs, mac)
+		}
+	}
+}
+
+// awaitReply reads packets until an ARP reply is received from ip, and
+// returns the sender's hardware address.
+func (c *Client) awaitReply(ip net.IP) (net.HardwareAddr, error) {
+	for {
+		p, _, err := c.Read()
+		if isFrameError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Operation != net_arp.OperationReply || !IPsEqual(p.SenderIP, ip) {
+			continue
+		}
+
+		return p.SenderHardwareAddr, nil
+	}
+}
+
+// isTimeout reports whether err is a net.Error caused by a timeout.
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// containsHardwareAddr reports whether mac is present in macs.
+func containsHardwareAddr(macs []net.HardwareAddr, mac net.HardwareAddr) bool {
+	for _, m := range macs {
+		if bytes.Equal(m, mac) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package arp
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+func TestResolveDuplicatePolicy(t *testing.T) {
+	peer2MAC := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x03}
+
+	tests := []struct {
+		name string
+		opts []Option
+		fn   func(t *testing.T, c *Client)
+	}{
+		{
+			name: "first",
+			fn: func(t *testing.T, c *Client) {
+				mac, err := c.Resolve(peerIP)
+				if err != nil {
+					t.Fatalf("failed to resolve: %v", err)
+				}
+				if !bytes.Equal(mac, peerMAC) {
+					t.Fatalf("unexpected hardware address: %s", mac)
+				}
+			},
+		},
+		{
+			name: "error",
+			opts: []Option{WithDuplicatePolicy(DuplicateError)},
+			fn: func(t *testing.T, c *Client) {
+				_, err := c.Resolve(peerIP)
+				if !errors.Is(err, ErrMultipleReplies) {
+					t.Fatalf("expected ErrMultipleReplies, got: %v", err)
+				}
+
+				var merr *MultipleRepliesError
+				if !errors.As(err, &merr) {
+					t.Fatalf("expected a *MultipleRepliesError, got: %T", err)
+				}
+				if !merr.IP.Equal(peerIP) || len(merr.HardwareAddrs) != 2 ||
+					!bytes.Equal(merr.HardwareAddrs[0], peerMAC) || !bytes.Equal(merr.HardwareAddrs[1], peer2MAC) {
+					t.Fatalf("unexpected error: %v", merr)
+				}
+			},
+		},
+		{
+			name: "all",
+			fn: func(t *testing.T, c *Client) {
+				macs, err := c.ResolveAll(peerIP)
+				if err != nil {
+					t.Fatalf("failed to resolve: %v", err)
+				}
+				if len(macs) != 2 || !bytes.Equal(macs[0], peerMAC) || !bytes.Equal(macs[1], peer2MAC) {
+					t.Fatalf("unexpected hardware addresses: %v", macs)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithDuplicateGrace(50 * time.Millisecond)}, tt.opts...)
+			c, conn := testClient(t, opts...)
+			defer c.Close()
+
+			// Two hosts answer for the same address, the second one twice.
+			conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+			conn.in <- arpFrame(t, net_arp.OperationReply, peer2MAC, peerIP, testMAC, testIP)
+			conn.in <- arpFrame(t, net_arp.OperationReply, peer2MAC, peerIP, testMAC, testIP)
+
+			tt.fn(t, c)
+
+			if p := nextWritten(t, conn); p.Operation != net_arp.OperationRequest || !p.TargetIP.Equal(peerIP) {
+				t.Fatalf("unexpected request: %+v", p)
+			}
+		})
+	}
+}
+
+func TestWithDuplicatePolicyInvalid(t *testing.T) {
+	if _, err := newConfig([]Option{WithDuplicatePolicy(DuplicatePolicy(-1))}); err == nil {
+		t.Fatal("expected an error for an invalid policy")
+	}
+}
@@ -0,0 +1,384 @@
+package arp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// maxScanHosts is the largest number of addresses ScanOccupied will sweep,
+// which corresponds to a /16 subnet.
+const maxScanHosts = 1 << 16
+
+// A sweep keeps at most scanWindow requests unanswered at a time, so the
+// replies to a large sweep do not overflow the socket's receive buffer. A
+// request which is not answered within about scanSlotTimeout frees its slot
+// for the next one, although its reply is still accepted until the
+// deadline. The slot is jittered by up to half of scanSlotTimeout either
+// way, so that requests which were sent together are not refilled in
+// lockstep bursts.
+const (
+	scanWindow      = 256
+	scanSlotTimeout = 20 * time.Millisecond
+)
+
+var (
+	// errNotIPv4Subnet is returned by ScanOccupied for non-IPv4 subnets.
+	errNotIPv4Subnet = errors.New("subnet is not an IPv4 subnet")
+
+	// errSubnetTooLarge is returned by ScanOccupied for subnets larger
+	// than a /16.
+	errSubnetTooLarge = errors.New("subnet is too large to scan, the limit is a /16")
+
+	// errUnboundedSweep is returned by ResolveMany when neither a timeout,
+	// a default timeout nor a read deadline bounds the sweep.
+	errUnboundedSweep = errors.New("sweep requires a timeout, default timeout, or read deadline")
+
+	// errNoSourceIPs is returned by WithSourceIPs when no address is given.
+	errNoSourceIPs = errors.New("at least one source IP address is required")
+
+	// errInvalidSourceIP is returned by WithSourceIP and WithSourceIPs for
+	// addresses which are not IPv4 addresses.
+	errInvalidSourceIP = errors.New("source IP address must be an IPv4 address")
+
+	// errNilTargetTimeout is returned by WithTargetTimeout for a nil
+	// function.
+	errNilTargetTimeout = errors.New("target timeout function must not be nil")
+)
+
+// A ResolveOption configures a single sweep by ResolveMany or ScanOccupied.
+type ResolveOption func(cfg *resolveConfig) error
+
+// resolveConfig contains the settings of a sweep which may be changed by
+// passing ResolveOptions.
+type resolveConfig struct {
+	// sources is nil unless WithSourceIPs was used.
+	sources []net.IP
+
+	// targetTimeout is nil unless WithTargetTimeout was used.
+	targetTimeout func(ip net.IP) time.Duration
+}
+
+// newResolveConfig applies opts on top of the default sweep settings.
+func newResolveConfig(opts []ResolveOption) (resolveConfig, error) {
+	var cfg resolveConfig
+	for _, o := range opts {
+		if err := o(&cfg); err != nil {
+			return resolveConfig{}, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// WithSourceIPs makes a sweep rotate the sender IP address of its requests
+// among ips, round-robin, instead of always using the Client's address.
+// Some devices rate-limit replies per requesting address, so spreading
+// requests over several addresses can improve coverage. This is intended
+// for penetration testing and lab use.
+//
+// Replies are addressed to the source IP of each request, so the host must
+// own or otherwise accept ARP replies for every address in ips. Replies
+// are still only read from the Client's interface, so with addresses the
+// host does not answer for, the sweep is blind. Every address must be an
+// IPv4 address.
+func WithSourceIPs(ips ...net.IP) ResolveOption {
+	return func(cfg *resolveConfig) error {
+		if len(ips) == 0 {
+			return errNoSourceIPs
+		}
+
+		sources := make([]net.IP, 0, len(ips))
+		for _, ip := range ips {
+			ip4 := ip.To4()
+			if ip4 == nil {
+				return errInvalidSourceIP
+			}
+			sources = append(sources, ip4)
+		}
+
+		cfg.sources = sources
+		return nil
+	}
+}
+
+// WithTargetTimeout bounds how long a sweep waits for each target
+// individually: fn is called once per target, and a target which does not
+// reply within the returned duration of its request is dropped from the
+// sweep early. This allows known slow hosts to be given more time while
+// others fail fast. If fn returns zero or a negative duration for a target,
+// it is only bounded by the timeout of the whole sweep, which also caps
+// every per-target window.
+func WithTargetTimeout(fn func(ip net.IP) time.Duration) ResolveOption {
+	return func(cfg *resolveConfig) error {
+		if fn == nil {
+			return errNilTargetTimeout
+		}
+
+		cfg.targetTimeout = fn
+		return nil
+	}
+}
+
+// ResolveMany sends an ARP request for each of ips, and collects replies
+// until every address has answered or timeout elapses. It returns the
+// hardware address of each IPv4 address which replied, keyed by the
+// address in string form. Addresses which did not reply are absent, and
+// are not an error. When several hosts reply for the same address, the
+// first reply wins.
+//
+// Requests are paced rather than sent in one burst: at most 256 of them
+// are unanswered at a time, and an unanswered request holds its place for
+// 10 to 30 milliseconds, drawn from the random source set with WithRand. A
+// sweep of many silent addresses, such as a sparse /16, therefore takes a
+// few seconds to send, and addresses which were not requested before
+// timeout elapsed are absent from the result.
+//
+// If timeout is zero, the default timeout set with WithDefaultTimeout is
+// used. A read deadline set on the Client applies if it is earlier. Silent
+// addresses never answer, so a sweep with no bound at all would block
+// forever: if timeout is zero, the default timeout is disabled with
+// WithDefaultTimeout(0) and no read deadline is set, an error is returned
+// instead.
+//
+// Like Resolve, ResolveMany must not be used concurrently with Read.
+// Options may be passed to change how requests are sent.
+func (c *Client) ResolveMany(ips []net.IP, timeout time.Duration, opts ...ResolveOption) (map[string]net.HardwareAddr, error) {
+	rcfg, err := newResolveConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	deadline, restore, err := c.bound(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	if deadline.IsZero() {
+		return nil, errUnboundedSweep
+	}
+
+	// queue holds the targets which have not been requested yet. pending
+	// holds those which were requested but have not replied, together with
+	// their own deadline, which is zero unless WithTargetTimeout applies.
+	// window holds the requests which occupy the send window, until the
+	// time at which their slot is freed if they remain unanswered.
+	queue := make([]net.IP, 0, len(ips))
+	seen := make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		k := ip.String()
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		queue = append(queue, ip)
+	}
+
+	pending := make(map[string]time.Time, len(queue))
+	window := make(map[string]time.Time, scanWindow)
+	found := make(map[string]net.HardwareAddr, len(queue))
+	sent := 0
+	for {
+		now := c.cfg.clock.Now()
+		for k, free := range window {
+			if !free.After(now) {
+				delete(window, k)
+			}
+		}
+
+		// Top up the window, so replies arrive at a pace the socket's
+		// receive buffer can absorb.
+		for len(queue) > 0 && len(window) < scanWindow {
+			ip := queue[0]
+			queue = queue[1:]
+
+			if len(rcfg.sources) == 0 {
+				err = c.Request(ip)
+			} else {
+				err = c.requestFrom(rcfg.sources[sent%len(rcfg.sources)], ip)
+			}
+			if err != nil {
+				return nil, err
+			}
+			sent++
+
+			var until time.Time
+			if rcfg.targetTimeout != nil {
+				if d := rcfg.targetTimeout(ip); d > 0 {
+					until = now.Add(d)
+				}
+			}
+
+			k := ip.String()
+			pending[k] = until
+			window[k] = now.Add(c.scanSlot())
+		}
+
+		// Drop targets whose own window has elapsed, and wake up when the
+		// next one does, or when the next slot of the window is freed.
+		next := expireTargets(pending, now, deadline)
+		if len(pending) == 0 && len(queue) == 0 {
+			break
+		}
+		if len(queue) > 0 {
+			for _, free := range window {
+				if next.IsZero() || free.Before(next) {
+					next = free
+				}
+			}
+		}
+		if err := c.p.SetReadDeadline(next); err != nil {
+			return nil, c.opError("set deadline", err)
+		}
+
+		p, _, err := c.Read()
+		if isFrameError(err) {
+			continue
+		}
+		if err != nil {
+			if !isTimeout(err) {
+				return nil, err
+			}
+			if ctx.Err() != nil || (!deadline.IsZero() && !c.cfg.clock.Now().Before(deadline)) {
+				break
+			}
+			continue
+		}
+
+		if p.Operation != net_arp.OperationReply {
+			continue
+		}
+
+		k := p.SenderIP.String()
+		if _, ok := pending[k]; !ok {
+			continue
+		}
+
+		delete(pending, k)
+		delete(window, k)
+		found[k] = p.SenderHardwareAddr
+	}
+
+	return found, nil
+}
+
+// scanSlot returns how long an unanswered request of a sweep occupies its
+// slot in the send window.
+func (c *Client) scanSlot() time.Duration {
+	return scanSlotTimeout/2 + c.randDuration(scanSlotTimeout)
+}
+
+// expireTargets removes the targets of pending whose deadline is not after
+// now, and returns the earliest of the remaining deadlines and the overall
+// deadline, either of which may be zero.
+func expireTargets(pending map[string]time.Time, now, deadline time.Time) time.Time {
+	next := deadline
+	for k, until := range pending {
+		if until.IsZero() {
+			continue
+		}
+		if !until.After(now) {
+			delete(pending, k)
+			continue
+		}
+		if next.IsZero() || until.Before(next) {
+			next = until
+		}
+	}
+
+	return next
+}
+
+// ScanOccupied ARP-sweeps subnet, and returns the sorted IPv4 addresses
+// which answered within timeout, as for a presence dashboard. The network
+// and broadcast addresses are not scanned, except for /31 subnets, where
+// both addresses are usable hosts (RFC 3021), and /32 subnets, which
+// contain a single address. The Client's own address is reported as
+// occupied without being scanned when it lies within subnet.
+//
+// timeout bounds the total time of the sweep, and opts change how requests
+// are sent, as for ResolveMany. Subnets larger than a /16 are rejected.
+func (c *Client) ScanOccupied(subnet *net.IPNet, timeout time.Duration, opts ...ResolveOption) ([]net.IP, error) {
+	hosts, err := subnetHosts(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		targets []net.IP
+		self    bool
+	)
+	for _, ip := range hosts {
+		if IPsEqual(ip, c.ip) {
+			self = true
+			continue
+		}
+		targets = append(targets, ip)
+	}
+
+	found, err := c.ResolveMany(targets, timeout, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	occupied := make([]net.IP, 0, len(found)+1)
+	if self {
+		occupied = append(occupied, c.ip)
+	}
+	for _, ip := range targets {
+		if _, ok := found[ip.String()]; ok {
+			occupied = append(occupied, ip)
+		}
+	}
+
+	sort.Slice(occupied, func(i, j int) bool {
+		return bytes.Compare(occupied[i], occupied[j]) < 0
+	})
+	return occupied, nil
+}
+
+// subnetHosts returns the host addresses of subnet, in ascending order.
+func subnetHosts(subnet *net.IPNet) ([]net.IP, error) {
+	ip4 := subnet.IP.To4()
+	ones, bits := subnet.Mask.Size()
+	if ip4 == nil || bits != 32 {
+		return nil, errNotIPv4Subnet
+	}
+
+	size := uint64(1) << uint(32-ones)
+	if size > maxScanHosts {
+		return nil, errSubnetTooLarge
+	}
+
+	first := binary.BigEndian.Uint32(ip4.Mask(subnet.Mask))
+	last := first + uint32(size-1)
+
+	// Skip the network and broadcast addresses when the subnet has room for
+	// them.
+	if size > 2 {
+		first++
+		last--
+	}
+
+	hosts := make([]net.IP, 0, last-first+1)
+	for n := uint64(first); n <= uint64(last); n++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(n))
+		hosts = append(hosts, ip)
+	}
+
+	return hosts, nil
+}
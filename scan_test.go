@@ -0,0 +1,274 @@
+package arp
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+func TestSubnetHosts(t *testing.T) {
+	tests := []struct {
+		name   string
+		subnet string
+		first  net.IP
+		last   net.IP
+		n      int
+		err    error
+	}{
+		{
+			name:   "/24 excludes network and broadcast",
+			subnet: "192.0.2.0/24",
+			first:  hostIP(1),
+			last:   hostIP(254),
+			n:      254,
+		},
+		{
+			name:   "/30",
+			subnet: "192.0.2.4/30",
+			first:  hostIP(5),
+			last:   hostIP(6),
+			n:      2,
+		},
+		{
+			name:   "/31 includes both addresses",
+			subnet: "192.0.2.6/31",
+			first:  hostIP(6),
+			last:   hostIP(7),
+			n:      2,
+		},
+		{
+			name:   "/32 single host",
+			subnet: "192.0.2.9/32",
+			first:  hostIP(9),
+			last:   hostIP(9),
+			n:      1,
+		},
+		{
+			name:   "/16",
+			subnet: "198.51.0.0/16",
+			first:  net.IPv4(198, 51, 0, 1).To4(),
+			last:   net.IPv4(198, 51, 255, 254).To4(),
+			n:      1<<16 - 2,
+		},
+		{
+			name:   "too large",
+			subnet: "198.50.0.0/15",
+			err:    errSubnetTooLarge,
+		},
+		{
+			name:   "IPv6",
+			subnet: "2001:db8::/120",
+			err:    errNotIPv4Subnet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, subnet, err := net.ParseCIDR(tt.subnet)
+			if err != nil {
+				t.Fatalf("failed to parse subnet: %v", err)
+			}
+
+			hosts, err := subnetHosts(subnet)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("unexpected error: want %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to list hosts: %v", err)
+			}
+
+			if len(hosts) != tt.n {
+				t.Fatalf("unexpected number of hosts: want %d, got %d", tt.n, len(hosts))
+			}
+			if !hosts[0].Equal(tt.first) || !hosts[len(hosts)-1].Equal(tt.last) {
+				t.Fatalf("unexpected host range: want %s-%s, got %s-%s",
+					tt.first, tt.last, hosts[0], hosts[len(hosts)-1])
+			}
+		})
+	}
+}
+
+func TestScanOccupied(t *testing.T) {
+	tests := []struct {
+		name   string
+		subnet string
+		answer []net.IP
+		// requested is the number of addresses which must be requested,
+		// skipped those which must not be, and want the occupied
+		// addresses.
+		requested int
+		skipped   []string
+		want      []net.IP
+	}{
+		{
+			name:   "/24",
+			subnet: "192.0.2.0/24",
+			answer: []net.IP{peerIP, hostIP(200)},
+			// Neither the network and broadcast addresses nor the
+			// Client's own address are requested.
+			requested: 253,
+			skipped:   []string{"192.0.2.0", "192.0.2.255", testIP.String()},
+			want:      []net.IP{testIP, peerIP, hostIP(200)},
+		},
+		{
+			name:      "/31",
+			subnet:    "198.51.100.6/31",
+			answer:    []net.IP{net.IPv4(198, 51, 100, 6).To4(), net.IPv4(198, 51, 100, 7).To4()},
+			requested: 2,
+			want:      []net.IP{net.IPv4(198, 51, 100, 6).To4(), net.IPv4(198, 51, 100, 7).To4()},
+		},
+		{
+			name:      "/32",
+			subnet:    "198.51.100.9/32",
+			answer:    []net.IP{net.IPv4(198, 51, 100, 9).To4()},
+			requested: 1,
+			want:      []net.IP{net.IPv4(198, 51, 100, 9).To4()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, conn := testClient(t)
+			defer c.Close()
+
+			_, subnet, err := net.ParseCIDR(tt.subnet)
+			if err != nil {
+				t.Fatalf("failed to parse subnet: %v", err)
+			}
+
+			answer := make(map[string]bool, len(tt.answer))
+			for _, ip := range tt.answer {
+				answer[ip.String()] = true
+			}
+
+			// Answer requests for the occupied addresses, and record every
+			// requested address until the sweep completes.
+			var (
+				mu        sync.Mutex
+				requested = make(map[string]bool)
+				done      = make(chan struct{})
+				wg        sync.WaitGroup
+			)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					var b []byte
+					select {
+					case b = <-conn.out:
+					case <-done:
+						return
+					}
+
+					f := new(ethernet.Frame)
+					p := new(net_arp.Packet)
+					if err := f.UnmarshalBinary(b); err != nil {
+						t.Errorf("failed to unmarshal written frame: %v", err)
+						return
+					}
+					if err := p.UnmarshalBinary(f.Payload); err != nil {
+						t.Errorf("failed to unmarshal written packet: %v", err)
+						return
+					}
+
+					mu.Lock()
+					requested[p.TargetIP.String()] = true
+					mu.Unlock()
+
+					if answer[p.TargetIP.String()] {
+						mac := net.HardwareAddr{0x02, 0, 0, 0, 0xff, p.TargetIP.To4()[3]}
+						conn.in <- arpFrame(t, net_arp.OperationReply, mac, p.TargetIP, testMAC, testIP)
+					}
+				}
+			}()
+
+			got, err := c.ScanOccupied(subnet, 200*time.Millisecond)
+			close(done)
+			wg.Wait()
+			if err != nil {
+				t.Fatalf("failed to scan: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Fatalf("unexpected occupied addresses:\n- want: %v\n-  got: %v", tt.want, got)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(requested) != tt.requested {
+				t.Fatalf("unexpected number of requests: want %d, got %d", tt.requested, len(requested))
+			}
+			for _, ip := range tt.skipped {
+				if requested[ip] {
+					t.Fatalf("unexpected request for %s", ip)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveManyUnbounded(t *testing.T) {
+	c, conn := testClient(t, WithDefaultTimeout(0))
+	defer c.Close()
+
+	_, err := c.ResolveMany([]net.IP{peerIP}, 0)
+	if !errors.Is(err, errUnboundedSweep) {
+		t.Fatalf("unexpected error: want %v, got %v", errUnboundedSweep, err)
+	}
+	select {
+	case <-conn.out:
+		t.Fatal("unbounded sweep sent a request")
+	default:
+	}
+
+	// A read deadline bounds the sweep instead.
+	if err := c.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	found, err := c.ResolveMany([]net.IP{peerIP}, 0)
+	if err != nil {
+		t.Fatalf("failed to resolve with a read deadline: %v", err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("unexpected addresses found: %v", found)
+	}
+}
+
+func TestScanSlotSeeded(t *testing.T) {
+	slots := func(seed int64) []time.Duration {
+		c, _ := testClient(t, WithRand(rand.New(rand.NewSource(seed))))
+		defer c.Close()
+
+		ds := make([]time.Duration, 0, 64)
+		for i := 0; i < cap(ds); i++ {
+			d := c.scanSlot()
+			if d < scanSlotTimeout/2 || d >= scanSlotTimeout*3/2 {
+				t.Fatalf("slot %v out of range", d)
+			}
+			ds = append(ds, d)
+		}
+		return ds
+	}
+
+	a, b := slots(1), slots(1)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("same seed produced different sweep pacing:\n- a: %v\n- b: %v", a, b)
+	}
+	if reflect.DeepEqual(a, slots(2)) {
+		t.Fatal("different seeds produced identical sweep pacing")
+	}
+
+	if _, err := newConfig([]Option{WithRand(nil)}); !errors.Is(err, errNilRand) {
+		t.Fatalf("unexpected error for a nil source: want %v, got %v", errNilRand, err)
+	}
+}
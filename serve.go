@@ -0,0 +1,87 @@
+package arp
+
+import (
+	"context"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// An ARPHandler handles ARP packets read by Serve.
+type ARPHandler interface {
+	ServeARP(c *Client, p *net_arp.Packet, f *ethernet.Frame)
+}
+
+// HandlerFunc is an adapter which allows an ordinary function to be used as
+// an ARPHandler.
+type HandlerFunc func(c *Client, p *net_arp.Packet, f *ethernet.Frame)
+
+// ServeARP calls fn(c, p, f).
+func (fn HandlerFunc) ServeARP(c *Client, p *net_arp.Packet, f *ethernet.Frame) {
+	fn(c, p, f)
+}
+
+// Serve reads ARP packets from the Client and passes each of them to h,
+// until reading from the socket fails, and returns that error. Frames which
+// cannot be decoded are written to the Logger and skipped, so a malformed
+// frame from another host does not stop a long-running responder. h is
+// called synchronously from the read loop, so a slow handler delays
+// reading.
+//
+// Serve owns the read side of the Client, so Read and Resolve must not be
+// used while it is running. Use a Node to answer requests and resolve
+// addresses over the same Client.
+func (c *Client) Serve(h ARPHandler) error {
+	for {
+		p, f, err := c.Read()
+		if isFrameError(err) {
+			c.cfg.logger.Printf("%s: serve: skipping frame: %v", c.name, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		h.ServeARP(c, p, f)
+	}
+}
+
+// ServeFor is like Serve, but also stops when ctx is done, in which case it
+// returns nil rather than an error. This allows time-boxed captures, such
+// as serving for 30 seconds using context.WithTimeout, without closing the
+// Client. The pending read is unblocked as soon as ctx is done, and the
+// Client's read deadline is restored before ServeFor returns.
+func (c *Client) ServeFor(ctx context.Context, h ARPHandler) error {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			_ = c.p.SetReadDeadline(c.cfg.clock.Now())
+		case <-stop:
+		}
+	}()
+
+	defer func() {
+		close(stop)
+		<-done
+		c.restoreReadDeadline()
+	}()
+
+	for {
+		p, f, err := c.Read()
+		if isFrameError(err) {
+			c.cfg.logger.Printf("%s: serve: skipping frame: %v", c.name, err)
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		h.ServeARP(c, p, f)
+	}
+}
@@ -0,0 +1,274 @@
+//go:build linux
+// +build linux
+
+package arp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/pefish/go-net-raw"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+// errNoSyscallConn is returned when a socket option must be applied to a
+// net.PacketConn which does not expose its file descriptor.
+var errNoSyscallConn = errors.New("net.PacketConn does not implement syscall.Conn")
+
+// listenPacket opens the raw socket used by Dial. The BPF filter of cfg,
+// if any, is attached before the socket is bound, so no unfiltered frames
+// are queued, and filtered reports whether it was.
+//
+// A net_raw.Conn does not expose its file descriptor, so when cfg requires
+// socket options which net_raw cannot set, a packetConn is used instead.
+func listenPacket(ifi *net.Interface, cfg config) (p net.PacketConn, filtered bool, err error) {
+	if cfg.priority == nil {
+		p, err = net_raw.ListenPacket(ifi, uint16(cfg.etherType), &net_raw.Config{
+			Filter: cfg.bpfFilter,
+		})
+	} else {
+		p, err = listenPacketConn(ifi, uint16(cfg.etherType), cfg.bpfFilter)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return p, cfg.bpfFilter != nil, nil
+}
+
+// setPriority sets SO_PRIORITY on the socket underlying p.
+func setPriority(p net.PacketConn, priority int) error {
+	sc, ok := p.(syscall.Conn)
+	if !ok {
+		return errNoSyscallConn
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PRIORITY, priority)
+	}); err != nil {
+		return err
+	}
+	if serr != nil {
+		return os.NewSyscallError("setsockopt", serr)
+	}
+
+	return nil
+}
+
+var _ net.PacketConn = &packetConn{}
+var _ syscall.Conn = &packetConn{}
+var _ bpf.Setter = &packetConn{}
+
+// packetConn is a minimal AF_PACKET net.PacketConn which, unlike
+// net_raw.Conn, implements syscall.Conn. Its addresses are *net_raw.Addr
+// values, so it is interchangeable with a net_raw.Conn for a Client.
+type packetConn struct {
+	ifi *net.Interface
+	f   *os.File
+	rc  syscall.RawConn
+	pbe uint16
+}
+
+// listenPacketConn opens a SOCK_RAW packet socket bound to ifi for proto,
+// with filter attached first unless it is nil.
+func listenPacketConn(ifi *net.Interface, proto uint16, filter []bpf.RawInstruction) (*packetConn, error) {
+	pbe := htons(proto)
+
+	sock, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := unix.SetNonblock(sock, true); err != nil {
+		_ = unix.Close(sock)
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+
+	// A non-blocking descriptor is registered with the runtime poller by
+	// os.NewFile, which enables deadlines.
+	f := os.NewFile(uintptr(sock), "arp-packet-socket")
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	p := &packetConn{
+		ifi: ifi,
+		f:   f,
+		rc:  rc,
+		pbe: pbe,
+	}
+
+	if filter != nil {
+		if err := p.SetBPF(filter); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	var berr error
+	if err := rc.Control(func(fd uintptr) {
+		berr = unix.Bind(int(fd), &unix.SockaddrLinklayer{
+			Protocol: pbe,
+			Ifindex:  ifi.Index,
+		})
+	}); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if berr != nil {
+		_ = f.Close()
+		return nil, os.NewSyscallError("bind", berr)
+	}
+
+	return p, nil
+}
+
+// ReadFrom implements the net.PacketConn ReadFrom method.
+func (p *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	var (
+		n   int
+		sa  unix.Sockaddr
+		err error
+	)
+	cerr := p.rc.Read(func(fd uintptr) bool {
+		n, sa, err = unix.Recvfrom(int(fd), b, 0)
+		// Let the poller wait for readiness on EAGAIN.
+		return err != unix.EAGAIN
+	})
+	if cerr != nil {
+		return 0, nil, cerr
+	}
+	if err != nil {
+		return 0, nil, os.NewSyscallError("recvfrom", err)
+	}
+
+	sall, ok := sa.(*unix.SockaddrLinklayer)
+	if !ok {
+		return n, nil, unix.EINVAL
+	}
+
+	mac := make(net.HardwareAddr, sall.Halen)
+	copy(mac, sall.Addr[:])
+	return n, &net_raw.Addr{HardwareAddr: mac}, nil
+}
+
+// WriteTo implements the net.PacketConn WriteTo method.
+func (p *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	a, ok := addr.(*net_raw.Addr)
+	if !ok || a.HardwareAddr == nil {
+		return 0, unix.EINVAL
+	}
+
+	var baddr [8]byte
+	copy(baddr[:], a.HardwareAddr)
+
+	var err error
+	cerr := p.rc.Write(func(fd uintptr) bool {
+		err = unix.Sendto(int(fd), b, 0, &unix.SockaddrLinklayer{
+			Ifindex:  p.ifi.Index,
+			Halen:    uint8(len(a.HardwareAddr)),
+			Addr:     baddr,
+			Protocol: p.pbe,
+		})
+		return err != unix.EAGAIN
+	})
+	if cerr != nil {
+		return 0, cerr
+	}
+	if err != nil {
+		return 0, os.NewSyscallError("sendto", err)
+	}
+
+	return len(b), nil
+}
+
+// Close implements the net.PacketConn Close method.
+func (p *packetConn) Close() error {
+	return p.f.Close()
+}
+
+// LocalAddr implements the net.PacketConn LocalAddr method.
+func (p *packetConn) LocalAddr() net.Addr {
+	return &net_raw.Addr{HardwareAddr: p.ifi.HardwareAddr}
+}
+
+// SetDeadline implements the net.PacketConn SetDeadline method.
+func (p *packetConn) SetDeadline(t time.Time) error {
+	return p.f.SetDeadline(t)
+}
+
+// SetReadDeadline implements the net.PacketConn SetReadDeadline method.
+func (p *packetConn) SetReadDeadline(t time.Time) error {
+	return p.f.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements the net.PacketConn SetWriteDeadline method.
+func (p *packetConn) SetWriteDeadline(t time.Time) error {
+	return p.f.SetWriteDeadline(t)
+}
+
+// SetPromiscuous enables or disables promiscuous mode on the interface.
+func (p *packetConn) SetPromiscuous(b bool) error {
+	mreq := unix.PacketMreq{
+		Ifindex: int32(p.ifi.Index),
+		Type:    unix.PACKET_MR_PROMISC,
+	}
+
+	membership := unix.PACKET_ADD_MEMBERSHIP
+	if !b {
+		membership = unix.PACKET_DROP_MEMBERSHIP
+	}
+
+	var err error
+	if cerr := p.rc.Control(func(fd uintptr) {
+		err = unix.SetsockoptPacketMreq(int(fd), unix.SOL_PACKET, membership, &mreq)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+// SetBPF attaches the classic BPF program filter to the socket.
+func (p *packetConn) SetBPF(filter []bpf.RawInstruction) error {
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: (*unix.SockFilter)(unsafe.Pointer(&filter[0])),
+	}
+
+	var err error
+	if cerr := p.rc.Control(func(fd uintptr) {
+		err = unix.SetsockoptSockFprog(int(fd), unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+// SyscallConn implements syscall.Conn.
+func (p *packetConn) SyscallConn() (syscall.RawConn, error) {
+	return p.rc, nil
+}
+
+// htons converts a short (uint16) from host-to-network byte order.
+func htons(i uint16) uint16 {
+	return (i<<8)&0xff00 | i>>8
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package arp
+
+import (
+	"errors"
+	"net"
+
+	"github.com/pefish/go-net-raw"
+)
+
+// errPriorityUnsupported is returned when packet priority is requested on a
+// platform which does not support SO_PRIORITY.
+var errPriorityUnsupported = errors.New("packet priority is only supported on Linux")
+
+// listenPacket opens the raw socket used by Dial. net_raw only attaches a
+// BPF filter at creation on Linux, so filtered is always false, and the
+// filter is attached by initFeatures instead.
+func listenPacket(ifi *net.Interface, cfg config) (p net.PacketConn, filtered bool, err error) {
+	p, err = net_raw.ListenPacket(ifi, uint16(cfg.etherType), nil)
+	return p, false, err
+}
+
+// setPriority is not supported on this platform.
+func setPriority(p net.PacketConn, priority int) error {
+	return errPriorityUnsupported
+}
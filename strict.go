@@ -0,0 +1,57 @@
+package arp
+
+import (
+	"errors"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// minPayloadLen is the minimum payload length of an untagged ethernet
+// frame. Shorter payloads are padded by the sender to reach the 60 byte
+// minimum frame length, excluding the frame check sequence.
+const minPayloadLen = 46
+
+// ErrTrailingData is returned by Read when the WithStrictFrames option is
+// in effect and an ARP frame carries unexpected data after its ARP packet.
+var ErrTrailingData = errors.New("unexpected trailing data after ARP packet")
+
+// WithStrictFrames makes Read reject ARP frames which carry unexpected data
+// after the ARP packet, returning an *OpError wrapping ErrTrailingData.
+// This is useful for conformance testing, and to flag malformed or crafted
+// frames. By default such data is silently ignored. The read loops of the
+// package, such as Resolve, ResolveMany, ScanOccupied, Monitor, Serve and
+// Node.Serve, skip rejected frames like any other frame which cannot be
+// decoded; Monitor and Serve also write them to the Logger.
+//
+// An ARP packet is 8 bytes plus twice its hardware and protocol address
+// lengths, which is 28 bytes for Ethernet and IPv4. Bytes following it are
+// accepted as padding only if they are all zero and the payload does not
+// exceed the 46 byte minimum ethernet payload. Anything else, such as a
+// longer payload or non-zero padding, counts as unexpected trailing data.
+// Note that some network drivers do not zero their padding, so strict mode
+// may reject frames from otherwise well behaved hosts.
+func WithStrictFrames() Option {
+	return func(cfg *config) error {
+		cfg.strict = true
+		return nil
+	}
+}
+
+// checkTrailing returns ErrTrailingData if payload, from which p was
+// decoded, carries data after p other than ethernet padding.
+func checkTrailing(p *net_arp.Packet, payload []byte) error {
+	n := 8 + 2*int(p.HardwareAddrLength) + 2*int(p.IPLength)
+	if len(payload) <= n {
+		return nil
+	}
+	if len(payload) > minPayloadLen {
+		return ErrTrailingData
+	}
+
+	for _, b := range payload[n:] {
+		if b != 0 {
+			return ErrTrailingData
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package arp
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+	"github.com/pefish/go-net-arp"
+)
+
+// malformedFrames returns frames which Read fails to decode: with and
+// without WithStrictFrames, respectively.
+func malformedFrames(t *testing.T) (garbage, truncated []byte) {
+	t.Helper()
+
+	p, err := net_arp.NewPacket(net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+	if err != nil {
+		t.Fatalf("failed to create packet: %v", err)
+	}
+	pb, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal packet: %v", err)
+	}
+
+	// A valid packet followed by non-zero padding.
+	garbage = append(pb, bytes.Repeat([]byte{0xff}, minPayloadLen-len(pb))...)
+
+	// A hardware address length of 16 needs more than the padded payload.
+	long := append([]byte(nil), garbage...)
+	long[4] = 16
+
+	return ethernetFrame(t, peerMAC, testMAC, garbage), ethernetFrame(t, peerMAC, testMAC, long)
+}
+
+func TestReadLoopsSkipMalformedFrames(t *testing.T) {
+	peer2IP := net.IPv4(192, 0, 2, 3).To4()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, c *Client, conn *testConn, bad []byte)
+	}{
+		{
+			name: "Resolve",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				conn.in <- bad
+				conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+
+				mac, err := c.Resolve(peerIP)
+				if err != nil {
+					t.Fatalf("failed to resolve: %v", err)
+				}
+				if !bytes.Equal(mac, peerMAC) {
+					t.Fatalf("unexpected hardware address: %s", mac)
+				}
+			},
+		},
+		{
+			name: "ResolveMany",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				for _, ip := range []net.IP{peerIP, peer2IP} {
+					conn.in <- bad
+					conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, ip, testMAC, testIP)
+				}
+
+				found, err := c.ResolveMany([]net.IP{peerIP, peer2IP}, time.Second)
+				if err != nil {
+					t.Fatalf("failed to resolve: %v", err)
+				}
+				if len(found) != 2 {
+					t.Fatalf("unexpected results: %v", found)
+				}
+			},
+		},
+		{
+			name: "ScanOccupied",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				conn.in <- bad
+				conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+
+				subnet := &net.IPNet{IP: net.IPv4(192, 0, 2, 0).To4(), Mask: net.CIDRMask(30, 32)}
+				ips, err := c.ScanOccupied(subnet, 200*time.Millisecond)
+				if err != nil {
+					t.Fatalf("failed to scan: %v", err)
+				}
+				if len(ips) != 2 || !ips[1].Equal(peerIP) {
+					t.Fatalf("unexpected occupied addresses: %v", ips)
+				}
+			},
+		},
+		{
+			name: "Monitor",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				events, stop := c.Monitor()
+				defer stop()
+
+				conn.in <- bad
+				conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+
+				select {
+				case ev, ok := <-events:
+					if !ok {
+						t.Fatal("monitor stopped")
+					}
+					if !ev.Packet.SenderIP.Equal(peerIP) {
+						t.Fatalf("unexpected event: %+v", ev.Packet)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("timed out waiting for an event")
+				}
+			},
+		},
+		{
+			name: "Serve",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				served := make(chan *net_arp.Packet, 1)
+				errC := make(chan error, 1)
+				go func() {
+					errC <- c.Serve(HandlerFunc(func(_ *Client, p *net_arp.Packet, _ *ethernet.Frame) {
+						served <- p
+					}))
+				}()
+
+				conn.in <- bad
+				conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+				waitServed(t, served)
+
+				_ = c.Close()
+				if err := <-errC; err == nil {
+					t.Fatal("expected an error after close")
+				}
+			},
+		},
+		{
+			name: "ServeFor",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				served := make(chan *net_arp.Packet, 1)
+				errC := make(chan error, 1)
+				go func() {
+					errC <- c.ServeFor(ctx, HandlerFunc(func(_ *Client, p *net_arp.Packet, _ *ethernet.Frame) {
+						served <- p
+					}))
+				}()
+
+				conn.in <- bad
+				conn.in <- arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP)
+				waitServed(t, served)
+
+				cancel()
+				if err := <-errC; err != nil {
+					t.Fatalf("failed to serve: %v", err)
+				}
+			},
+		},
+		{
+			name: "Node.Serve",
+			fn: func(t *testing.T, c *Client, conn *testConn, bad []byte) {
+				n := NewNode(c, testIP)
+				errC := make(chan error, 1)
+				go func() { errC <- n.Serve() }()
+
+				conn.in <- bad
+				conn.in <- arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, ethernet.BroadcastHardwareAddr, testIP)
+
+				p := nextWritten(t, conn)
+				if p.Operation != net_arp.OperationReply || !p.SenderIP.Equal(testIP) {
+					t.Fatalf("unexpected reply: %+v", p)
+				}
+
+				_ = c.Close()
+				<-errC
+			},
+		},
+	}
+
+	garbage, truncated := malformedFrames(t)
+	frames := []struct {
+		name   string
+		b      []byte
+		strict bool
+	}{
+		{name: "trailing garbage", b: garbage, strict: true},
+		{name: "truncated", b: truncated},
+	}
+
+	for _, f := range frames {
+		for _, tt := range tests {
+			t.Run(f.name+"/"+tt.name, func(t *testing.T) {
+				var opts []Option
+				if f.strict {
+					opts = append(opts, WithStrictFrames())
+				}
+
+				c, conn := testClient(t, opts...)
+				defer c.Close()
+
+				tt.fn(t, c, conn, f.b)
+			})
+		}
+	}
+}
+
+// waitServed waits for a handler to receive a packet on served.
+func waitServed(t *testing.T, served <-chan *net_arp.Packet) {
+	t.Helper()
+
+	select {
+	case p := <-served:
+		if !p.SenderIP.Equal(peerIP) {
+			t.Fatalf("unexpected packet: %+v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the handler")
+	}
+}
@@ -0,0 +1,196 @@
+package arp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pefish/go-ethernet"
+)
+
+// errNilWriter is returned by MirrorTo when the writer is nil.
+var errNilWriter = errors.New("writer must not be nil")
+
+// A tap observes the raw bytes of every frame read by the Client. n is the
+// length of the frame reported by the net.PacketConn, which exceeds len(b)
+// if the frame was truncated to the read buffer.
+type tap func(t time.Time, b []byte, n int)
+
+// taps is the set of taps installed on a Client.
+type taps struct {
+	// mu is held while taps are called, so removeTap does not return
+	// while a call is in progress.
+	mu sync.Mutex
+	m  map[*tap]struct{}
+}
+
+// addTap installs fn, and returns a function which removes it.
+func (c *Client) addTap(fn tap) func() {
+	c.taps.mu.Lock()
+	if c.taps.m == nil {
+		c.taps.m = make(map[*tap]struct{})
+	}
+	c.taps.m[&fn] = struct{}{}
+	c.taps.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.taps.mu.Lock()
+			delete(c.taps.m, &fn)
+			c.taps.mu.Unlock()
+		})
+	}
+}
+
+// tapFrame passes frame b, read at t with length n, to all installed taps.
+func (c *Client) tapFrame(t time.Time, b []byte, n int) {
+	c.taps.mu.Lock()
+	defer c.taps.mu.Unlock()
+
+	for fn := range c.taps.m {
+		(*fn)(t, b, n)
+	}
+}
+
+// truncate returns the frame read into buf, given the length n reported by
+// the net.PacketConn, which may exceed len(buf) if the frame was truncated.
+func truncate(buf []byte, n int) []byte {
+	if n > len(buf) {
+		return buf
+	}
+	return buf[:n]
+}
+
+// CaptureTo writes every frame subsequently read by the Client to w in
+// libpcap format, which can be opened by tools such as Wireshark and
+// tcpdump. The pcap global header is written immediately, followed by a
+// timestamped packet record for each frame. Frames are captured as read
+// from the socket, including those Read discards, and are truncated to
+// the Client's read buffer size. The original length of each record is the
+// length reported by the net.PacketConn, which is the on-wire length for
+// connections which report truncated frames.
+//
+// CaptureTo does not read on its own: it records the frames read by Read
+// and the methods built on it, such as Resolve, Serve and Monitor. Records
+// are written synchronously by the reading goroutine, so a slow w slows
+// down reading. If a write fails, the error is written to the Logger and
+// capturing stops.
+//
+// Call the returned function to stop capturing. Once it returns, w is no
+// longer written to.
+func (c *Client) CaptureTo(w io.Writer) (func(), error) {
+	pw, err := newPcapWriter(w, readBufferSize)
+	if err != nil {
+		return nil, c.opError("capture", err)
+	}
+
+	var failed bool
+	stop := c.addTap(func(t time.Time, b []byte, n int) {
+		if failed {
+			return
+		}
+
+		if err := pw.writePacket(t, b, n); err != nil {
+			c.cfg.logger.Printf("%s: capture stopped: %v", c.name, err)
+			failed = true
+		}
+	})
+
+	return stop, nil
+}
+
+// mirrorBuffer is the number of frames MirrorTo queues for writing before
+// it drops frames.
+const mirrorBuffer = 256
+
+// MirrorTo writes the raw bytes of every ARP frame subsequently read by the
+// Client to w, one Write call per frame, until the returned function is
+// called. Unlike CaptureTo, no headers or timestamps are added, so w may be
+// a TAP device or another sink which re-injects the frames as they were
+// received on the wire. Frames whose EtherType is not the configured ARP
+// EtherType are not mirrored.
+//
+// Like CaptureTo, MirrorTo records the frames read by Read and the methods
+// built on it. Frames are queued and written by a separate goroutine, so a
+// slow w does not block reading: when the queue is full, frames are
+// dropped. If a write fails, the error is written to the Logger and
+// mirroring stops.
+//
+// Call the returned function to stop mirroring. Once it returns, w is no
+// longer written to, and it reports the number of frames which were dropped
+// because the queue was full. Later calls report the same number.
+func (c *Client) MirrorTo(w io.Writer) (func() int, error) {
+	if w == nil {
+		return nil, c.opError("mirror", errNilWriter)
+	}
+
+	frames := make(chan []byte, mirrorBuffer)
+	done := make(chan struct{})
+	var dropped int
+
+	remove := c.addTap(func(t time.Time, b []byte, _ int) {
+		if et, ok := frameEtherType(b); !ok || et != c.cfg.etherType {
+			return
+		}
+
+		select {
+		case frames <- append([]byte(nil), b...):
+		default:
+			dropped++
+		}
+	})
+
+	go func() {
+		defer close(done)
+
+		var failed bool
+		for b := range frames {
+			if failed {
+				continue
+			}
+
+			if _, err := w.Write(b); err != nil {
+				c.cfg.logger.Printf("%s: mirror stopped: %v", c.name, err)
+				failed = true
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() int {
+		once.Do(func() {
+			// No tap call is in progress once remove returns, so the
+			// channel may be closed and dropped read safely.
+			remove()
+			close(frames)
+			<-done
+
+			if dropped > 0 {
+				c.cfg.logger.Printf("%s: mirror dropped %d frames", c.name, dropped)
+			}
+		})
+		return dropped
+	}, nil
+}
+
+// frameEtherType returns the EtherType of the raw ethernet frame b,
+// following any VLAN tags.
+func frameEtherType(b []byte) (ethernet.EtherType, bool) {
+	n := headerLen
+	for {
+		if len(b) < n {
+			return 0, false
+		}
+
+		et := ethernet.EtherType(binary.BigEndian.Uint16(b[n-2 : n]))
+		switch et {
+		case ethernet.EtherTypeVLAN, ethernet.EtherTypeServiceVLAN:
+			n += vlanTagLen
+		default:
+			return et, true
+		}
+	}
+}
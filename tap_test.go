@@ -0,0 +1,207 @@
+package arp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// fixedClock is a Clock whose current time never changes.
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time                         { return c.t }
+func (c fixedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func TestCaptureToReplay(t *testing.T) {
+	now := time.Date(2026, time.January, 2, 3, 4, 5, 6000, time.UTC)
+	c, conn := testClient(t, WithClock(fixedClock{t: now}))
+	defer c.Close()
+	conn.reportLen = true
+
+	// A frame larger than the read buffer is truncated in the capture, but
+	// keeps its on-wire length.
+	long := ethernetFrame(t, peerMAC, testMAC, make([]byte, 2*readBufferSize))
+	frames := [][]byte{
+		arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, testMAC, testIP),
+		long,
+		arpFrame(t, net_arp.OperationReply, peerMAC, peerIP, testMAC, testIP),
+	}
+
+	var buf bytes.Buffer
+	stop, err := c.CaptureTo(&buf)
+	if err != nil {
+		t.Fatalf("failed to start capture: %v", err)
+	}
+
+	for _, f := range frames {
+		conn.in <- f
+	}
+	// Read until the reply, since the long frame may be rejected as
+	// malformed, which does not prevent its capture.
+	for {
+		p, _, err := c.Read()
+		if isFrameError(err) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if p.Operation == net_arp.OperationReply {
+			break
+		}
+	}
+	stop()
+
+	capture := append([]byte(nil), buf.Bytes()...)
+
+	// Check the global header, then each record header.
+	hdr := capture[:pcapHeaderLen]
+	if got := binary.LittleEndian.Uint32(hdr[0:4]); got != pcapMagic {
+		t.Fatalf("unexpected magic: %#x", got)
+	}
+	if got := binary.LittleEndian.Uint32(hdr[16:20]); got != readBufferSize {
+		t.Fatalf("unexpected snapshot length: %d", got)
+	}
+	if got := binary.LittleEndian.Uint32(hdr[20:24]); got != pcapLinkTypeEthernet {
+		t.Fatalf("unexpected link type: %d", got)
+	}
+
+	records := capture[pcapHeaderLen:]
+	for i, f := range frames {
+		if len(records) < pcapRecordLen {
+			t.Fatalf("record %d: capture is too short", i)
+		}
+		rec := records[:pcapRecordLen]
+
+		var (
+			sec  = binary.LittleEndian.Uint32(rec[0:4])
+			usec = binary.LittleEndian.Uint32(rec[4:8])
+			incl = int(binary.LittleEndian.Uint32(rec[8:12]))
+			orig = int(binary.LittleEndian.Uint32(rec[12:16]))
+		)
+		if int64(sec) != now.Unix() || int(usec) != now.Nanosecond()/1000 {
+			t.Fatalf("record %d: unexpected timestamp %d.%06d", i, sec, usec)
+		}
+
+		want := truncate(f, readBufferSize)
+		if incl != len(want) || orig != len(f) {
+			t.Fatalf("record %d: unexpected lengths: want %d/%d, got %d/%d",
+				i, len(want), len(f), incl, orig)
+		}
+		records = records[pcapRecordLen+incl:]
+	}
+	if len(records) != 0 {
+		t.Fatalf("unexpected %d trailing bytes", len(records))
+	}
+
+	// Replay the capture, which yields the captured bytes of each frame.
+	p, err := ReplayPcap(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatalf("failed to open replay: %v", err)
+	}
+	defer p.Close()
+
+	b := make([]byte, 1500)
+	for i, f := range frames {
+		n, _, err := p.ReadFrom(b)
+		if err != nil {
+			t.Fatalf("record %d: failed to replay: %v", i, err)
+		}
+		if want, got := truncate(f, readBufferSize), b[:n]; !bytes.Equal(want, got) {
+			t.Fatalf("record %d: unexpected frame:\n- want: %x\n-  got: %x", i, want, got)
+		}
+	}
+	if _, _, err := p.ReadFrom(b); err != io.EOF {
+		t.Fatalf("expected io.EOF after the last record, got: %v", err)
+	}
+}
+
+// blockingWriter is an io.Writer which blocks every Write until release is
+// closed, and reports each Write on started.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	writes int
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return len(b), nil
+}
+
+func TestMirrorToSlowWriterDrops(t *testing.T) {
+	c, conn := testClient(t)
+	defer c.Close()
+
+	w := &blockingWriter{
+		started: make(chan struct{}, 2*mirrorBuffer),
+		release: make(chan struct{}),
+	}
+	stop, err := c.MirrorTo(w)
+	if err != nil {
+		t.Fatalf("failed to start mirror: %v", err)
+	}
+
+	frame := arpFrame(t, net_arp.OperationRequest, peerMAC, peerIP, testMAC, testIP)
+	read := func(n int) {
+		t.Helper()
+
+		done := make(chan error, 1)
+		go func() {
+			for i := 0; i < n; i++ {
+				conn.in <- frame
+				if _, _, err := c.Read(); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("failed to read: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("read loop blocked by a slow mirror writer")
+		}
+	}
+
+	// Block the writer on the first frame, then fill the queue and
+	// overflow it; reading must not stall meanwhile.
+	const extra = 10
+	read(1)
+	select {
+	case <-w.started:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the first mirrored write")
+	}
+	read(mirrorBuffer + extra)
+
+	close(w.release)
+	if got := stop(); got != extra {
+		t.Fatalf("unexpected number of dropped frames: want %d, got %d", extra, got)
+	}
+	if got := stop(); got != extra {
+		t.Fatalf("unexpected number of dropped frames on a second stop: want %d, got %d", extra, got)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if want := 1 + mirrorBuffer; w.writes != want {
+		t.Fatalf("unexpected number of mirrored frames: want %d, got %d", want, w.writes)
+	}
+}
@@ -0,0 +1,34 @@
+package arp
+
+import (
+	"net"
+
+	"github.com/pefish/go-net-arp"
+)
+
+// Packet is an ARP packet, as read by Read and written by WriteTo. It is an
+// alias of net_arp.Packet, so callers need not import go-net-arp.
+type Packet = net_arp.Packet
+
+// Operation is an ARP operation, such as request or reply. It is an alias
+// of net_arp.Operation.
+type Operation = net_arp.Operation
+
+// Operation constants which indicate an ARP request or reply.
+const (
+	OperationRequest = net_arp.OperationRequest
+	OperationReply   = net_arp.OperationReply
+)
+
+// Ensure the aliases stay interchangeable with the types of go-net-arp.
+var (
+	_ *net_arp.Packet   = (*Packet)(nil)
+	_ net_arp.Operation = OperationRequest
+	_ Operation         = net_arp.OperationReply
+)
+
+// NewPacket creates a new Packet from an input Operation and hardware/IPv4
+// address values for both a sender and target, as by net_arp.NewPacket.
+func NewPacket(op Operation, srcHW net.HardwareAddr, srcIP net.IP, dstHW net.HardwareAddr, dstIP net.IP) (*Packet, error) {
+	return net_arp.NewPacket(op, srcHW, srcIP, dstHW, dstIP)
+}
@@ -0,0 +1,147 @@
+package arp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pefish/go-ethernet"
+)
+
+// errInvalidVLANFilter is returned when an empty VLAN filter, or one with
+// a reserved VLAN ID, is configured.
+var errInvalidVLANFilter = errors.New("VLAN filter must contain at least one ID, and only IDs below 4095")
+
+// trafficClasses maps each IEEE 802.1p priority to its recommended traffic
+// class on a bridge port with 8 traffic classes, as in the default priority
+// to traffic class mapping of IEEE 802.1Q. Priority 1 (background) ranks
+// below priority 0 (best effort).
+var trafficClasses = [...]int{
+	ethernet.PriorityBestEffort:           1,
+	ethernet.PriorityBackground:           0,
+	ethernet.PriorityExcellentEffort:      2,
+	ethernet.PriorityCriticalApplications: 3,
+	ethernet.PriorityVideo:                4,
+	ethernet.PriorityVoice:                5,
+	ethernet.PriorityInternetworkControl:  6,
+	ethernet.PriorityNetworkControl:       7,
+}
+
+// TrafficClass returns the traffic class, from 0 (lowest) to 7 (highest),
+// which IEEE 802.1Q recommends for frames tagged with v on a port with 8
+// traffic classes, so monitoring tools can classify ARP traffic by its
+// intended QoS class. A nil v, as for an untagged frame, is treated as
+// priority 0 (best effort), which maps to class 1.
+//
+// The mapping is only the standard default: switches commonly have fewer
+// queues, or are configured with a different mapping.
+func TrafficClass(v *ethernet.VLAN) int {
+	if v == nil {
+		return trafficClasses[ethernet.PriorityBestEffort]
+	}
+
+	// The priority is a 3 bit field, so only values up to 7 can be
+	// decoded from a tag.
+	return trafficClasses[v.Priority&0x7]
+}
+
+// ValidateVLANs checks that the VLAN tags of f are well-formed, so invalid
+// frames are caught before marshaling. The returned error describes the
+// first problem found, and wraps ethernet.ErrInvalidVLAN. The rules are:
+//   - A service tag (802.1ad, QinQ) must be followed by a customer tag.
+//   - Each tag must have a priority of at most 7, and an ID below 4095.
+//   - The EtherType of f must not be a VLAN TPID, since the payload would
+//     then be decoded as another tag, out of order.
+func ValidateVLANs(f *ethernet.Frame) error {
+	if f.ServiceVLAN != nil && f.VLAN == nil {
+		return fmt.Errorf("%w: service VLAN %d without customer VLAN", ethernet.ErrInvalidVLAN, f.ServiceVLAN.ID)
+	}
+
+	tags := []struct {
+		name string
+		v    *ethernet.VLAN
+	}{
+		{name: "service", v: f.ServiceVLAN},
+		{name: "customer", v: f.VLAN},
+	}
+	for _, t := range tags {
+		switch {
+		case t.v == nil:
+		case t.v.Priority > ethernet.PriorityNetworkControl:
+			return fmt.Errorf("%w: %s VLAN %d has priority %d, want at most %d",
+				ethernet.ErrInvalidVLAN, t.name, t.v.ID, t.v.Priority, ethernet.PriorityNetworkControl)
+		case t.v.ID >= ethernet.VLANMax:
+			return fmt.Errorf("%w: %s VLAN ID %d is reserved, want below %d",
+				ethernet.ErrInvalidVLAN, t.name, t.v.ID, ethernet.VLANMax)
+		}
+	}
+
+	switch f.EtherType {
+	case ethernet.EtherTypeVLAN, ethernet.EtherTypeServiceVLAN:
+		return fmt.Errorf("%w: EtherType %#04x is a VLAN TPID, use the VLAN fields to add tags",
+			ethernet.ErrInvalidVLAN, uint16(f.EtherType))
+	}
+
+	return nil
+}
+
+// WithVLANFilter makes Read, and the methods built on it such as Monitor,
+// Serve and Resolve, discard frames whose VLAN ID is not one of ids. This
+// saves the work of parsing and delivering ARP packets from unrelated VLANs
+// when a Client is dialed on a busy trunk port. Frames are filtered right
+// after their ethernet header is decoded, so taps added with MirrorTo still
+// see every frame.
+//
+// By default the ID of the customer (802.1Q) tag is matched, which is the
+// innermost tag of QinQ frames. Use WithOuterVLANFilter to match the
+// service (802.1ad) tag instead. Frames which do not carry the matched tag,
+// including all untagged frames, are discarded while a filter is set; note
+// that many drivers strip the tag of the native VLAN of a port, so its
+// frames arrive untagged. Each of ids must be below 4095.
+func WithVLANFilter(ids ...uint16) Option {
+	return func(cfg *config) error {
+		if len(ids) == 0 {
+			return errInvalidVLANFilter
+		}
+
+		filter := make(map[uint16]struct{}, len(ids))
+		for _, id := range ids {
+			if id >= ethernet.VLANMax {
+				return errInvalidVLANFilter
+			}
+
+			filter[id] = struct{}{}
+		}
+
+		cfg.vlanFilter = filter
+		return nil
+	}
+}
+
+// WithOuterVLANFilter makes WithVLANFilter match the ID of the service
+// (802.1ad) tag of QinQ frames, instead of the customer tag. Frames without
+// a service tag are then discarded, even if their customer tag matches. It
+// has no effect unless WithVLANFilter is also used.
+func WithOuterVLANFilter() Option {
+	return func(cfg *config) error {
+		cfg.vlanFilterOuter = true
+		return nil
+	}
+}
+
+// matchVLAN reports whether f passes the VLAN filter of cfg, if any.
+func (cfg *config) matchVLAN(f *ethernet.Frame) bool {
+	if cfg.vlanFilter == nil {
+		return true
+	}
+
+	v := f.VLAN
+	if cfg.vlanFilterOuter {
+		v = f.ServiceVLAN
+	}
+	if v == nil {
+		return false
+	}
+
+	_, ok := cfg.vlanFilter[v.ID]
+	return ok
+}
@@ -0,0 +1,140 @@
+package arp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pefish/go-ethernet"
+)
+
+func TestTrafficClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     *ethernet.VLAN
+		class int
+	}{
+		{name: "untagged", class: 1},
+		{name: "best effort", v: &ethernet.VLAN{Priority: ethernet.PriorityBestEffort}, class: 1},
+		{name: "background", v: &ethernet.VLAN{Priority: ethernet.PriorityBackground}, class: 0},
+		{name: "excellent effort", v: &ethernet.VLAN{Priority: ethernet.PriorityExcellentEffort}, class: 2},
+		{name: "critical applications", v: &ethernet.VLAN{Priority: ethernet.PriorityCriticalApplications}, class: 3},
+		{name: "video", v: &ethernet.VLAN{Priority: ethernet.PriorityVideo}, class: 4},
+		{name: "voice", v: &ethernet.VLAN{Priority: ethernet.PriorityVoice}, class: 5},
+		{name: "internetwork control", v: &ethernet.VLAN{Priority: ethernet.PriorityInternetworkControl}, class: 6},
+		{name: "network control", v: &ethernet.VLAN{Priority: ethernet.PriorityNetworkControl}, class: 7},
+		{name: "ID does not matter", v: &ethernet.VLAN{Priority: ethernet.PriorityVoice, ID: 100}, class: 5},
+		// Only the 3 bits of the priority field are used.
+		{name: "out of range", v: &ethernet.VLAN{Priority: 8 | ethernet.PriorityVideo}, class: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrafficClass(tt.v); got != tt.class {
+				t.Fatalf("unexpected traffic class: want %d, got %d", tt.class, got)
+			}
+		})
+	}
+}
+
+func TestValidateVLANs(t *testing.T) {
+	tests := []struct {
+		name string
+		f    *ethernet.Frame
+		ok   bool
+	}{
+		{
+			name: "untagged",
+			f:    &ethernet.Frame{EtherType: ethernet.EtherTypeARP},
+			ok:   true,
+		},
+		{
+			name: "customer tag",
+			f: &ethernet.Frame{
+				VLAN:      &ethernet.VLAN{Priority: ethernet.PriorityNetworkControl, ID: 4094},
+				EtherType: ethernet.EtherTypeARP,
+			},
+			ok: true,
+		},
+		{
+			name: "QinQ",
+			f: &ethernet.Frame{
+				ServiceVLAN: &ethernet.VLAN{ID: 100},
+				VLAN:        &ethernet.VLAN{ID: 10},
+				EtherType:   ethernet.EtherTypeARP,
+			},
+			ok: true,
+		},
+		{
+			name: "VLAN ID 0 with priority",
+			f: &ethernet.Frame{
+				VLAN:      &ethernet.VLAN{Priority: ethernet.PriorityVoice},
+				EtherType: ethernet.EtherTypeARP,
+			},
+			ok: true,
+		},
+		{
+			name: "service tag without customer tag",
+			f: &ethernet.Frame{
+				ServiceVLAN: &ethernet.VLAN{ID: 100},
+				EtherType:   ethernet.EtherTypeARP,
+			},
+		},
+		{
+			name: "customer priority too high",
+			f: &ethernet.Frame{
+				VLAN:      &ethernet.VLAN{Priority: 8, ID: 10},
+				EtherType: ethernet.EtherTypeARP,
+			},
+		},
+		{
+			name: "service priority too high",
+			f: &ethernet.Frame{
+				ServiceVLAN: &ethernet.VLAN{Priority: 8, ID: 100},
+				VLAN:        &ethernet.VLAN{ID: 10},
+				EtherType:   ethernet.EtherTypeARP,
+			},
+		},
+		{
+			name: "reserved customer ID",
+			f: &ethernet.Frame{
+				VLAN:      &ethernet.VLAN{ID: ethernet.VLANMax},
+				EtherType: ethernet.EtherTypeARP,
+			},
+		},
+		{
+			name: "reserved service ID",
+			f: &ethernet.Frame{
+				ServiceVLAN: &ethernet.VLAN{ID: ethernet.VLANMax},
+				VLAN:        &ethernet.VLAN{ID: 10},
+				EtherType:   ethernet.EtherTypeARP,
+			},
+		},
+		{
+			name: "VLAN EtherType",
+			f:    &ethernet.Frame{EtherType: ethernet.EtherTypeVLAN},
+		},
+		{
+			name: "service VLAN EtherType",
+			f: &ethernet.Frame{
+				VLAN:      &ethernet.VLAN{ID: 10},
+				EtherType: ethernet.EtherTypeServiceVLAN,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVLANs(tt.f)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("failed to validate VLANs: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ethernet.ErrInvalidVLAN) {
+				t.Fatalf("expected an error wrapping ethernet.ErrInvalidVLAN, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package arp
+
+import (
+	"errors"
+	"net"
+)
+
+// errNoneResolved is returned by Warmup when none of its addresses
+// resolved.
+var errNoneResolved = errors.New("none of the warmup addresses resolved")
+
+// Warmup resolves each of ips, such as the default gateway and DNS
+// servers, and stores the results in the Client's Cache, so later calls to
+// ResolveCached for them are cache hits. It is built on ResolveMany, and
+// waits for the default timeout set with WithDefaultTimeout at most. If the
+// default timeout is disabled, a read deadline must be set on the Client.
+//
+// Partial success is not an error: Warmup returns the hardware address of
+// each address which resolved, keyed by the address in string form, and
+// addresses which did not resolve are absent. An error is only returned if
+// ips is not empty and none of them resolved, or if sending or reading
+// fails. Like Resolve, Warmup must not be used concurrently with Read.
+func (c *Client) Warmup(ips []net.IP) (map[string]net.HardwareAddr, error) {
+	found, err := c.ResolveMany(ips, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) > 0 && len(found) == 0 {
+		return nil, c.opError("warmup", errNoneResolved)
+	}
+
+	now := c.cfg.clock.Now()
+	for ip, mac := range found {
+		c.cache.Store(net.ParseIP(ip), mac, now)
+	}
+
+	return found, nil
+}
+
+// ResolveCached returns the hardware address of ip from the Client's Cache,
+// and otherwise resolves it with Resolve and stores the result in the
+// Cache.
+func (c *Client) ResolveCached(ip net.IP) (net.HardwareAddr, error) {
+	if mac, ok := c.cache.Lookup(ip); ok {
+		return mac, nil
+	}
+
+	mac, err := c.Resolve(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Store(ip, mac, c.cfg.clock.Now())
+	return mac, nil
+}
@@ -0,0 +1,415 @@
+//go:build linux
+// +build linux
+
+package arp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/pefish/go-net-raw"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+// Sizes of the AF_XDP rings and UMEM used by DialXDP. Ring sizes must be
+// powers of two, and frames must hold a full ethernet frame.
+const (
+	xdpRingSize  = 2048
+	xdpNumFrames = xdpRingSize
+	xdpFrameSize = 2048
+)
+
+var (
+	// errXDPBusy is returned when every UMEM frame is waiting for the
+	// kernel to complete its transmission.
+	errXDPBusy = errors.New("AF_XDP transmit ring is full")
+
+	// errXDPFrameTooLarge is returned when a frame does not fit in a UMEM
+	// frame.
+	errXDPFrameTooLarge = errors.New("frame is too large for AF_XDP transmit buffer")
+
+	// errXDPClosed is returned when writing to a closed AF_XDP socket.
+	errXDPClosed = errors.New("AF_XDP socket is closed")
+
+	// errXDPPriority is returned when a packet priority is requested for
+	// frames sent through AF_XDP, which bypasses the queueing disciplines
+	// that honor it.
+	errXDPPriority = errors.New("packet priority does not apply to frames sent through AF_XDP")
+)
+
+// DialXDP creates a new Client using the specified network interface,
+// like Dial, except that frames are transmitted through an AF_XDP socket
+// bound to queueID of the interface. Writing to the XDP transmit ring
+// bypasses most of the kernel network stack, which allows generating ARP
+// traffic at a much higher rate, for instance in lab load generators.
+// Frames are still read through a regular packet socket, and the Client's
+// API is unchanged.
+//
+// AF_XDP requires Linux 4.18 or newer, CAP_NET_RAW and CAP_NET_ADMIN (or
+// CAP_SYS_ADMIN on older kernels), and a queueID which exists on the
+// interface. If the socket cannot be set up, DialXDP returns an error
+// describing the failed step, and Dial can be used instead. Write
+// deadlines have no effect, since writes to the ring never block: if the
+// ring is full, WriteTo fails.
+//
+// WithPromiscuous, WithBPFFilter and socket statistics apply to the packet
+// socket used for reading. WithPacketPriority cannot be applied, since
+// AF_XDP bypasses the queueing disciplines which honor it, so a warning is
+// written to the Logger, see Capabilities.
+func DialXDP(ifi *net.Interface, queueID int, opts ...Option) (*Client, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := newXDPSocket(ifi, queueID)
+	if err != nil {
+		return nil, err
+	}
+
+	rx, filtered, err := listenPacket(ifi, cfg)
+	if err != nil {
+		_ = tx.Close()
+		return nil, err
+	}
+
+	p := &xdpConn{PacketConn: rx, tx: tx}
+	c, err := newFromConn(ifi, p, cfg, filtered)
+	if err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// An xdpConn is a net.PacketConn which reads from a packet socket, and
+// writes to an AF_XDP socket. The optional socket features checked by
+// initFeatures are forwarded to the packet socket.
+type xdpConn struct {
+	net.PacketConn
+	tx *xdpSocket
+}
+
+var (
+	_ promiscuousSetter = &xdpConn{}
+	_ bpf.Setter        = &xdpConn{}
+	_ statser           = &xdpConn{}
+	_ syscall.Conn      = &xdpConn{}
+)
+
+// SetPromiscuous enables or disables promiscuous mode using the packet
+// socket.
+func (c *xdpConn) SetPromiscuous(b bool) error {
+	ps, ok := c.PacketConn.(promiscuousSetter)
+	if !ok {
+		return fmt.Errorf("promiscuous mode not supported by %T", c.PacketConn)
+	}
+	return ps.SetPromiscuous(b)
+}
+
+// SetBPF attaches filter to the packet socket, which filters the frames
+// read by the Client.
+func (c *xdpConn) SetBPF(filter []bpf.RawInstruction) error {
+	s, ok := c.PacketConn.(bpf.Setter)
+	if !ok {
+		return fmt.Errorf("BPF filters not supported by %T", c.PacketConn)
+	}
+	return s.SetBPF(filter)
+}
+
+// Stats returns the statistics of the packet socket.
+func (c *xdpConn) Stats() (*net_raw.Stats, error) {
+	s, ok := c.PacketConn.(statser)
+	if !ok {
+		return nil, fmt.Errorf("statistics not supported by %T", c.PacketConn)
+	}
+	return s.Stats()
+}
+
+// SyscallConn implements syscall.Conn. Socket options set through it would
+// only apply to the packet socket used for reading, which is why it fails:
+// the only such option, SO_PRIORITY, has no effect on AF_XDP transmission.
+func (c *xdpConn) SyscallConn() (syscall.RawConn, error) {
+	return nil, errXDPPriority
+}
+
+// WriteTo implements the net.PacketConn WriteTo method. The frame is sent
+// as is, so addr is not used.
+func (c *xdpConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if err := c.tx.send(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes both sockets.
+func (c *xdpConn) Close() error {
+	err := c.PacketConn.Close()
+	if terr := c.tx.Close(); err == nil {
+		err = terr
+	}
+	return err
+}
+
+// SetDeadline implements the net.PacketConn SetDeadline method. Only reads
+// are affected.
+func (c *xdpConn) SetDeadline(t time.Time) error {
+	return c.PacketConn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements the net.PacketConn SetWriteDeadline method.
+// Writes never block, so it has no effect.
+func (c *xdpConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// An xdpSocket is a transmit-only AF_XDP socket with its own UMEM.
+type xdpSocket struct {
+	fd int
+
+	// umem holds xdpNumFrames frames of xdpFrameSize bytes, and free lists
+	// the offsets of frames not owned by the kernel.
+	umem []byte
+	free []uint64
+
+	// tx and cr are the mapped transmit and completion rings.
+	tx, cr   []byte
+	txProd   *uint32
+	txCons   *uint32
+	txDesc   uintptr
+	crProd   *uint32
+	crCons   *uint32
+	crDesc   uintptr
+	inFlight int
+
+	// mu serializes send and Close, and guards closed, so the rings and
+	// UMEM are not unmapped while a frame is being queued.
+	mu     sync.Mutex
+	closed bool
+}
+
+// newXDPSocket creates an AF_XDP socket bound to queueID of ifi, with a
+// UMEM and transmit ring.
+func newXDPSocket(ifi *net.Interface, queueID int) (*xdpSocket, error) {
+	fd, err := unix.Socket(unix.AF_XDP, unix.SOCK_RAW, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket AF_XDP", err)
+	}
+
+	s := &xdpSocket{fd: fd}
+	if err := s.setup(ifi, queueID); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// setup registers the UMEM, creates and maps the rings, and binds s.
+func (s *xdpSocket) setup(ifi *net.Interface, queueID int) error {
+	umem, err := unix.Mmap(-1, 0, xdpNumFrames*xdpFrameSize,
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap UMEM", err)
+	}
+	s.umem = umem
+
+	reg := unix.XDPUmemReg{
+		Addr: uint64(uintptr(unsafe.Pointer(&umem[0]))),
+		Len:  uint64(len(umem)),
+		Size: xdpFrameSize,
+	}
+	if err := setsockopt(s.fd, unix.XDP_UMEM_REG, unsafe.Pointer(&reg), unsafe.Sizeof(reg)); err != nil {
+		return os.NewSyscallError("setsockopt XDP_UMEM_REG", err)
+	}
+
+	// The kernel requires a fill ring for every UMEM, even though it is not
+	// used for transmitting.
+	for _, opt := range []int{unix.XDP_UMEM_FILL_RING, unix.XDP_UMEM_COMPLETION_RING, unix.XDP_TX_RING} {
+		if err := unix.SetsockoptInt(s.fd, unix.SOL_XDP, opt, xdpRingSize); err != nil {
+			return os.NewSyscallError("setsockopt XDP ring size", err)
+		}
+	}
+
+	off, err := mmapOffsets(s.fd)
+	if err != nil {
+		return os.NewSyscallError("getsockopt XDP_MMAP_OFFSETS", err)
+	}
+
+	s.tx, err = unix.Mmap(s.fd, unix.XDP_PGOFF_TX_RING,
+		int(off.Tx.Desc)+xdpRingSize*int(unsafe.Sizeof(unix.XDPDesc{})),
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap XDP transmit ring", err)
+	}
+	s.txProd = (*uint32)(unsafe.Pointer(&s.tx[off.Tx.Producer]))
+	s.txCons = (*uint32)(unsafe.Pointer(&s.tx[off.Tx.Consumer]))
+	s.txDesc = uintptr(off.Tx.Desc)
+
+	s.cr, err = unix.Mmap(s.fd, unix.XDP_UMEM_PGOFF_COMPLETION_RING,
+		int(off.Cr.Desc)+xdpRingSize*8,
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap XDP completion ring", err)
+	}
+	s.crProd = (*uint32)(unsafe.Pointer(&s.cr[off.Cr.Producer]))
+	s.crCons = (*uint32)(unsafe.Pointer(&s.cr[off.Cr.Consumer]))
+	s.crDesc = uintptr(off.Cr.Desc)
+
+	s.free = make([]uint64, 0, xdpNumFrames)
+	for i := 0; i < xdpNumFrames; i++ {
+		s.free = append(s.free, uint64(i*xdpFrameSize))
+	}
+
+	if err := unix.Bind(s.fd, &unix.SockaddrXDP{
+		Ifindex: uint32(ifi.Index),
+		QueueID: uint32(queueID),
+	}); err != nil {
+		return os.NewSyscallError("bind AF_XDP", err)
+	}
+
+	return nil
+}
+
+// send copies b into a free UMEM frame, places it on the transmit ring and
+// wakes up the kernel.
+func (s *xdpSocket) send(b []byte) error {
+	if len(b) > xdpFrameSize {
+		return errXDPFrameTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errXDPClosed
+	}
+
+	s.reclaim()
+	if len(s.free) == 0 {
+		// Give the kernel a chance to complete pending transmissions.
+		s.kick()
+		s.reclaim()
+		if len(s.free) == 0 {
+			return errXDPBusy
+		}
+	}
+
+	prod := atomic.LoadUint32(s.txProd)
+	if prod-atomic.LoadUint32(s.txCons) >= xdpRingSize {
+		return errXDPBusy
+	}
+
+	addr := s.free[len(s.free)-1]
+	s.free = s.free[:len(s.free)-1]
+	copy(s.umem[addr:addr+xdpFrameSize], b)
+
+	desc := (*unix.XDPDesc)(unsafe.Pointer(&s.tx[s.txDesc+uintptr(prod&(xdpRingSize-1))*unsafe.Sizeof(unix.XDPDesc{})]))
+	desc.Addr = addr
+	desc.Len = uint32(len(b))
+	desc.Options = 0
+
+	// Publish the descriptor only once it is written.
+	atomic.StoreUint32(s.txProd, prod+1)
+	s.inFlight++
+
+	s.kick()
+	return nil
+}
+
+// reclaim returns frames whose transmission completed to the free list.
+// The caller must hold s.mu.
+func (s *xdpSocket) reclaim() {
+	cons := atomic.LoadUint32(s.crCons)
+	prod := atomic.LoadUint32(s.crProd)
+	for ; cons != prod; cons++ {
+		idx := uintptr(cons & (xdpRingSize - 1))
+		addr := *(*uint64)(unsafe.Pointer(&s.cr[s.crDesc+idx*8]))
+		s.free = append(s.free, addr)
+		s.inFlight--
+	}
+	atomic.StoreUint32(s.crCons, cons)
+}
+
+// kick asks the kernel to process the transmit ring. Errors indicating the
+// kernel is busy are ignored, since the frames stay queued.
+func (s *xdpSocket) kick() {
+	_, _, _ = unix.Syscall6(unix.SYS_SENDTO, uintptr(s.fd), 0, 0, unix.MSG_DONTWAIT, 0, 0)
+}
+
+// Close unmaps the rings and UMEM, and closes the socket. It waits for a
+// concurrent send to complete, and later sends fail.
+func (s *xdpSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errXDPClosed
+	}
+	s.closed = true
+
+	for _, m := range [][]byte{s.tx, s.cr} {
+		if m != nil {
+			_ = unix.Munmap(m)
+		}
+	}
+	s.tx, s.cr = nil, nil
+
+	err := unix.Close(s.fd)
+
+	// The UMEM may only be released once the socket no longer references
+	// it.
+	if s.umem != nil {
+		_ = unix.Munmap(s.umem)
+		s.umem = nil
+	}
+	return err
+}
+
+// mmapOffsets retrieves the ring offsets of an AF_XDP socket. Kernels older
+// than 5.4 do not report the flags offset, which changes the layout.
+func mmapOffsets(fd int) (unix.XDPMmapOffsets, error) {
+	var raw [16]uint64
+	size := uint32(unsafe.Sizeof(raw))
+	if _, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), unix.SOL_XDP, unix.XDP_MMAP_OFFSETS,
+		uintptr(unsafe.Pointer(&raw[0])), uintptr(unsafe.Pointer(&size)), 0); errno != 0 {
+		return unix.XDPMmapOffsets{}, errno
+	}
+
+	// Each ring has producer, consumer and descriptor offsets, followed by
+	// the flags offset on newer kernels.
+	n := 4
+	if size == 12*8 {
+		n = 3
+	}
+	ring := func(i int) unix.XDPRingOffset {
+		return unix.XDPRingOffset{
+			Producer: raw[i*n],
+			Consumer: raw[i*n+1],
+			Desc:     raw[i*n+2],
+		}
+	}
+
+	return unix.XDPMmapOffsets{
+		Rx: ring(0),
+		Tx: ring(1),
+		Fr: ring(2),
+		Cr: ring(3),
+	}, nil
+}
+
+// setsockopt sets an AF_XDP socket option from a struct.
+func setsockopt(fd, opt int, v unsafe.Pointer, size uintptr) error {
+	if _, _, errno := unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), unix.SOL_XDP, uintptr(opt), uintptr(v), size, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package arp
+
+import (
+	"errors"
+	"net"
+)
+
+// errXDPUnsupported is returned by DialXDP on platforms other than Linux.
+var errXDPUnsupported = errors.New("AF_XDP is only supported on Linux, use Dial instead")
+
+// DialXDP is only supported on Linux. On other platforms it returns an
+// error, and Dial should be used instead.
+func DialXDP(ifi *net.Interface, queueID int, opts ...Option) (*Client, error) {
+	return nil, errXDPUnsupported
+}